@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/cache"
+	"github.com/spf13/cobra"
+)
+
+func newCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the Redis cache",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "flush",
+		Short: "Delete every cached list, item and page",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			client, err := repository.NewRedisDB(cmd.Context(), cfg.Redis)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			deleted, err := cache.Flush(cmd.Context(), client)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "deleted %d keys matching %s\n", deleted, cache.Pattern)
+			return nil
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/migration"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+	"strconv"
+)
+
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply or roll back the embedded database migrations",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply every pending migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(cmd, (*migration.Migrator).Up)
+			},
+		},
+		&cobra.Command{
+			Use:   "down [steps]",
+			Short: "Roll back the latest migrations, one by default",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				steps := 1
+				if len(args) == 1 {
+					n, err := strconv.Atoi(args[0])
+					if err != nil {
+						return fmt.Errorf("steps must be a number: %w", err)
+					}
+					steps = n
+				}
+
+				return withMigrator(cmd, func(m *migration.Migrator) error {
+					return m.Down(steps)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Print the schema version",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(cmd, func(*migration.Migrator) error { return nil })
+			},
+		},
+	)
+
+	return cmd
+}
+
+// withMigrator runs the action and prints the resulting schema status.
+func withMigrator(cmd *cobra.Command, action func(*migration.Migrator) error) error {
+	_, postgres, err := connectPostgres(cmd.Context())
+	if err != nil {
+		return err
+	}
+	defer postgres.Close()
+
+	migrator, err := migration.NewMigrator(cmd.Context(), postgres)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if err = action(migrator); err != nil {
+		return err
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "version: %d\nlatest: %d\ndirty: %t\npending: %t\n",
+		status.Version, status.Latest, status.Dirty, status.Pending())
+	return nil
+}
+
+// prepareSchema applies pending migrations when auto_migrate is on, then makes sure the
+// application does not serve a schema it was not written for.
+func prepareSchema(ctx context.Context, cfg config.PostgresConfig, postgres *sqlx.DB) error {
+	migrator, err := migration.NewMigrator(ctx, postgres)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if cfg.AutoMigrate {
+		if err = migrator.Up(); err != nil {
+			return err
+		}
+	}
+
+	return migrator.Check()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"os"
+	"strings"
+)
+
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "app",
+		Short:        "Todo REST API server and administration tools",
+		SilenceUsage: true,
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&configOptions.File, "config", "", "base config file (default config/config.yml)")
+	flags.StringVar(&configOptions.Profile, "profile", "", "profile merged over the base file: dev, test or prod (env "+config.EnvPrefix+"_PROFILE)")
+	flags.StringArrayVar(&configOptions.Files, "config-overlay", nil, "extra config file merged over the others, can be repeated")
+	flags.StringArrayVar(&configOptions.Overrides, "set", nil, "override a key, e.g. --set server.port=9000, can be repeated")
+
+	root.AddCommand(
+		newServeCommand(),
+		newMigrateCommand(),
+		newUserCommand(),
+		newTokenCommand(),
+		newCacheCommand(),
+		newConfigCommand(),
+	)
+
+	return root
+}
+
+// configOptions are filled from the persistent flags, so every command reads the config
+// the same way.
+var configOptions config.Options
+
+func loadConfig() (config.Config, error) {
+	cfg, err := config.NewConfig(configOptions)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// connectPostgres reads the config and connects to the database it names.
+func connectPostgres(ctx context.Context) (config.Config, *sqlx.DB, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	postgres, err := repository.NewPostgresDB(ctx, cfg.Postgres)
+	return cfg, postgres, err
+}
+
+// readPassword returns the flag value or, when it is empty, asks for the password on the
+// terminal without echoing it, or reads the first line of stdin when it is not a terminal,
+// so passwords do not end up in the shell history or the process list.
+func readPassword(cmd *cobra.Command, flag string) (string, error) {
+	if flag != "" {
+		return flag, nil
+	}
+
+	if in, ok := cmd.InOrStdin().(*os.File); ok && term.IsTerminal(int(in.Fd())) {
+		fmt.Fprint(cmd.ErrOrStderr(), "password: ")
+		password, err := term.ReadPassword(int(in.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return "", err
+		}
+
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password given, write it to stdin")
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/middlewares"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/lifecycle"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/metrics"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/logstore"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/auth"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/logs"
+	"github.com/go-redis/redis/v8"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os/signal"
+	"syscall"
+)
+
+func newServeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(cmd.Context())
+		},
+	}
+}
+
+// serve runs until SIGINT or SIGTERM. The components are started in the order they are added
+// below and stopped in reverse, so requests in flight are drained before the databases they
+// use are closed, and the logs are flushed last.
+func serve(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	logger, err := logging.New(cfg.Log)
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+
+	// until MongoDB is connected, and after it is closed, the application logs to the other sinks
+	zap.ReplaceGlobals(logger.Logger)
+
+	var (
+		store         = config.NewStore(cfg, configOptions)
+		manager       = lifecycle.NewManager()
+		logCollection *mongo.Collection
+		logSink       *logging.MongoDBSink
+		logRepo       *logstore.LogMongo
+		postgres      *sqlx.DB
+		postgresStats prometheus.Collector
+		activeUsers   *auth.ActiveUsers
+		redisClient   *redis.Client
+		services      *service.Service
+		srv           *api.Server
+	)
+
+	if cfg.Log.Mongo.Enabled {
+		manager.Add("mongo log sink", func(ctx context.Context) error {
+			if logCollection, err = repository.NewMongoDB(ctx, cfg.Mongo); err != nil {
+				return err
+			}
+
+			logRepo = logstore.NewLogMongo(logCollection)
+			if err = logRepo.EnsureIndexes(ctx, cfg.Mongo.Retention.TTL); err != nil {
+				return errors.Join(err, logCollection.Database().Client().Disconnect(ctx))
+			}
+
+			logSink = logging.NewMongoDBSink(logCollection, cfg.Mongo.Sink)
+			withMongo, err := logger.WithMongo(logSink)
+			if err != nil {
+				return errors.Join(err, logSink.Close(ctx), logCollection.Database().Client().Disconnect(ctx))
+			}
+
+			zap.ReplaceGlobals(withMongo)
+			return nil
+		}, func(ctx context.Context) error {
+			zap.ReplaceGlobals(logger.Logger)
+
+			return errors.Join(logSink.Close(ctx), logCollection.Database().Client().Disconnect(ctx))
+		})
+
+		if cfg.Mongo.Retention.MaxSizeMB > 0 {
+			manager.AddBackground("log retention", func(ctx context.Context) error {
+				logs.NewRetention(logRepo, cfg.Mongo.Retention, zap.S()).Run(ctx)
+				return nil
+			})
+		}
+	}
+
+	manager.Add("postgres", func(ctx context.Context) error {
+		if postgres, err = repository.NewPostgresDB(ctx, cfg.Postgres); err != nil {
+			return err
+		}
+
+		if err = prepareSchema(ctx, cfg.Postgres, postgres); err != nil {
+			return errors.Join(err, postgres.Close())
+		}
+
+		postgresStats = collectors.NewDBStatsCollector(postgres.DB, "postgres")
+		activeUsers = auth.NewActiveUsers(sql.NewSessionPostgres(postgres), cfg.Metrics.ActiveUsersInterval, zap.S())
+		if err = metrics.Register(postgresStats, activeUsers); err != nil {
+			return errors.Join(err, postgres.Close())
+		}
+		return nil
+	}, func(ctx context.Context) error {
+		metrics.Unregister(postgresStats, activeUsers)
+		return postgres.Close()
+	})
+	manager.AddCheck("postgres", func(ctx context.Context) error {
+		return postgres.PingContext(ctx)
+	})
+	manager.AddBackground("active users gauge", func(ctx context.Context) error {
+		activeUsers.Run(ctx)
+		return nil
+	})
+
+	manager.Add("redis", func(ctx context.Context) error {
+		redisClient, err = repository.NewRedisDB(ctx, cfg.Redis)
+		return err
+	}, func(ctx context.Context) error {
+		return redisClient.Close()
+	})
+	manager.AddCheck("redis", func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	})
+
+	manager.Add("services", func(ctx context.Context) error {
+		if services, err = service.NewService(cfg, postgres, redisClient, zap.S()); err != nil {
+			return err
+		}
+
+		store.OnChange(func(cfg config.Config) {
+			if l, err := zapcore.ParseLevel(cfg.Log.Level); err == nil {
+				logger.Level.SetLevel(l)
+			}
+			services.CacheTTL.Set(cfg.Cache.TTL)
+		})
+		return nil
+	}, nil)
+
+	if cfg.Reminders.Enabled {
+		manager.AddBackground("reminders", func(ctx context.Context) error {
+			services.Reminders.Run(ctx)
+			return nil
+		})
+	}
+
+	manager.AddBackground("config watcher", func(ctx context.Context) error {
+		// the application keeps running with the config it has
+		if err := store.Watch(ctx, zap.S()); err != nil {
+			zap.S().Errorf("config is not watched for changes: %s", err.Error())
+		}
+		return nil
+	})
+
+	manager.Add("http server", func(ctx context.Context) error {
+		srv = api.NewServer(store, middlewares.NewUserAuthMiddleware(services.AuthService))
+		srv.HandleHealth(manager.Ready)
+		srv.HandleAuth(services.AuthService)
+		srv.HandleLists(services.ListService)
+		srv.HandleItems(services.ItemService)
+		srv.HandleSearch(services.SearchService)
+		srv.HandleLogLevel(logger.Level)
+		if logRepo != nil {
+			srv.HandleLogs(logs.NewLogService(logRepo))
+		}
+
+		listener, err := srv.Listen()
+		if err != nil {
+			return err
+		}
+
+		manager.Go("http server", func() error {
+			return srv.Serve(listener)
+		})
+		zap.S().Infow("server started", "addr", listener.Addr().String())
+		return nil
+	}, func(ctx context.Context) error {
+		return srv.Shutdown(ctx)
+	})
+
+	return manager.Run(ctx, cfg.Server.ShutdownTimeout)
+}
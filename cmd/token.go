@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/auth"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+func newTokenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage access tokens",
+	}
+
+	var ttl time.Duration
+
+	issue := &cobra.Command{
+		Use:   "issue <username>",
+		Short: "Issue a token pair for a service account without its password",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withAuthService(cmd, func(s *auth.ImplAuthorizationService) error {
+				tokens, err := s.IssueToken(cmd.Context(), args[0], ttl)
+				if err != nil {
+					return err
+				}
+
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(tokens)
+			})
+		},
+	}
+	issue.Flags().DurationVar(&ttl, "ttl", 30*24*time.Hour, "lifetime of the access and refresh tokens")
+
+	cmd.AddCommand(issue)
+
+	return cmd
+}
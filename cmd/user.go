@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/auth"
+	"github.com/spf13/cobra"
+)
+
+func newUserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage user accounts",
+	}
+
+	cmd.AddCommand(newUserCreateCommand(), newUserResetPasswordCommand(), newUserDisableCommand(),
+		newUserAdminCommand("grant-admin", true), newUserAdminCommand("revoke-admin", false))
+
+	return cmd
+}
+
+func newUserCreateCommand() *cobra.Command {
+	var input todo.SignUpInput
+
+	cmd := &cobra.Command{
+		Use:   "create <username>",
+		Short: "Create a user, asking for the password or reading it from stdin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			password, err := readPassword(cmd, input.Password)
+			if err != nil {
+				return err
+			}
+
+			input.Username, input.Password = args[0], password
+			if input.Name == "" {
+				input.Name = input.Username
+			}
+
+			return withAuthService(cmd, func(s *auth.ImplAuthorizationService) error {
+				id, err := s.CreateUser(cmd.Context(), input)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "created user %s with id %d\n", input.Username, id)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&input.Name, "name", "", "display name, the username by default")
+	cmd.Flags().StringVar(&input.Password, "password", "", "password; insecure, it is visible in the shell history and the process list")
+
+	return cmd
+}
+
+func newUserResetPasswordCommand() *cobra.Command {
+	var password string
+
+	cmd := &cobra.Command{
+		Use:   "reset-password <username>",
+		Short: "Set a new password and sign the user out everywhere",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			password, err := readPassword(cmd, password)
+			if err != nil {
+				return err
+			}
+
+			return withAuthService(cmd, func(s *auth.ImplAuthorizationService) error {
+				if err := s.ResetPassword(cmd.Context(), args[0], password); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "password of %s is reset\n", args[0])
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&password, "password", "", "new password; insecure, it is visible in the shell history and the process list")
+
+	return cmd
+}
+
+func newUserDisableCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable <username>",
+		Short: "Block sign-in for the user and revoke their sessions",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withAuthService(cmd, func(s *auth.ImplAuthorizationService) error {
+				if err := s.DisableUser(cmd.Context(), args[0]); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "user %s is disabled\n", args[0])
+				return nil
+			})
+		},
+	}
+}
+
+func newUserAdminCommand(use string, admin bool) *cobra.Command {
+	short, done := "Revoke access to the admin endpoints", "is no longer an administrator"
+	if admin {
+		short, done = "Grant access to the admin endpoints", "is an administrator"
+	}
+
+	return &cobra.Command{
+		Use:   use + " <username>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withAuthService(cmd, func(s *auth.ImplAuthorizationService) error {
+				if err := s.SetAdmin(cmd.Context(), args[0], admin); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "user %s %s\n", args[0], done)
+				return nil
+			})
+		},
+	}
+}
+
+func withAuthService(cmd *cobra.Command, action func(*auth.ImplAuthorizationService) error) error {
+	cfg, postgres, err := connectPostgres(cmd.Context())
+	if err != nil {
+		return err
+	}
+	defer postgres.Close()
+
+	authService, err := service.NewAuthService(cfg.Auth, postgres)
+	if err != nil {
+		return err
+	}
+
+	return action(authService)
+}
@@ -2,65 +2,232 @@ package config
 
 import (
 	"fmt"
-	"github.com/spf13/viper"
-	"os"
+	"net"
+	"time"
 )
 
+// Config is read from config/config.yml, the profile file and the environment, see NewConfig.
+// The mapstructure tags are the keys of the files; yaml tags only keep `config print` in the
+// same shape.
 type Config struct {
-	Postgres PostgresConfig
-	Redis    RedisConfig
-	Mongo    MongoConfig
+	Server    ServerConfig   `mapstructure:"server" yaml:"server"`
+	Postgres  PostgresConfig `mapstructure:"postgres" yaml:"postgres"`
+	Redis     RedisConfig    `mapstructure:"redis" yaml:"redis"`
+	Mongo     MongoConfig    `mapstructure:"mongo" yaml:"mongo"`
+	Auth      AuthConfig     `mapstructure:"auth" yaml:"auth"`
+	Reminders ReminderConfig `mapstructure:"reminders" yaml:"reminders"`
+	Metrics   MetricsConfig  `mapstructure:"metrics" yaml:"metrics"`
+
+	// the sections below are reloaded at runtime, see Store
+	Log       LogConfig       `mapstructure:"log" yaml:"log"`
+	Cache     CacheConfig     `mapstructure:"cache" yaml:"cache"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit" yaml:"rate_limit"`
+	CORS      CORSConfig      `mapstructure:"cors" yaml:"cors"`
+	Features  FeatureFlags    `mapstructure:"features" yaml:"features"`
+}
+
+// ServerConfig tunes the HTTP server. Request deadlines stay below WriteTimeout, so a timed
+// out request still gets an error response.
+type ServerConfig struct {
+	Host           string        `mapstructure:"host" yaml:"host"`
+	Port           string        `mapstructure:"port" yaml:"port" binding:"required"`
+	ReadTimeout    time.Duration `mapstructure:"read_timeout" yaml:"read_timeout" binding:"required,gt=0"`
+	WriteTimeout   time.Duration `mapstructure:"write_timeout" yaml:"write_timeout" binding:"required,gt=0"`
+	MaxHeaderBytes int           `mapstructure:"max_header_bytes" yaml:"max_header_bytes" binding:"required,gt=0"`
+	AuthTimeout    time.Duration `mapstructure:"auth_timeout" yaml:"auth_timeout" binding:"required,gt=0,ltfield=WriteTimeout"`
+	RequestTimeout time.Duration `mapstructure:"request_timeout" yaml:"request_timeout" binding:"required,gt=0,ltfield=WriteTimeout"`
+	SearchTimeout  time.Duration `mapstructure:"search_timeout" yaml:"search_timeout" binding:"required,gt=0,ltfield=WriteTimeout"`
+	// ShutdownTimeout bounds draining the requests in flight and closing the backends.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" yaml:"shutdown_timeout" binding:"required,gt=0"`
+}
+
+func (c ServerConfig) Addr() string {
+	return net.JoinHostPort(c.Host, c.Port)
 }
 
 type PostgresConfig struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host        string `mapstructure:"host" yaml:"host" binding:"required"`
+	Port        string `mapstructure:"port" yaml:"port" binding:"required"`
+	Username    string `mapstructure:"username" yaml:"username" binding:"required"`
+	Password    string `mapstructure:"password" yaml:"password"`
+	DBName      string `mapstructure:"dbname" yaml:"dbname" binding:"required"`
+	SSLMode     string `mapstructure:"sslmode" yaml:"sslmode" binding:"required,oneof=disable allow prefer require verify-ca verify-full"`
+	AutoMigrate bool   `mapstructure:"auto_migrate" yaml:"auto_migrate"`
 }
 
 type RedisConfig struct {
-	Address  string
-	Password string
-	DB       int
+	Host     string `mapstructure:"host" yaml:"host" binding:"required"`
+	Port     string `mapstructure:"port" yaml:"port" binding:"required"`
+	Password string `mapstructure:"password" yaml:"password"`
+	DB       int    `mapstructure:"db" yaml:"db" binding:"min=0"`
+	Codec    string `mapstructure:"codec" yaml:"codec" binding:"required,oneof=json msgpack"`
+}
+
+func (c RedisConfig) Addr() string {
+	return net.JoinHostPort(c.Host, c.Port)
 }
 
 type MongoConfig struct {
-	DB       string
-	MongoURL string
+	Host      string               `mapstructure:"host" yaml:"host" binding:"required"`
+	Port      string               `mapstructure:"port" yaml:"port" binding:"required"`
+	DBName    string               `mapstructure:"dbname" yaml:"dbname" binding:"required"`
+	Sink      MongoSinkConfig      `mapstructure:"sink" yaml:"sink"`
+	Retention MongoRetentionConfig `mapstructure:"retention" yaml:"retention"`
+}
+
+// MongoSinkConfig tunes how logs are written to MongoDB. Entries wait in a queue of
+// QueueSize and are inserted BatchSize at a time, at least every FlushInterval. Entries the
+// full queue cannot take are dropped; batches MongoDB rejects are appended to FallbackFile,
+// or dropped when it is empty.
+type MongoSinkConfig struct {
+	QueueSize     int           `mapstructure:"queue_size" yaml:"queue_size" binding:"required,min=1"`
+	BatchSize     int           `mapstructure:"batch_size" yaml:"batch_size" binding:"required,min=1,ltefield=QueueSize"`
+	FlushInterval time.Duration `mapstructure:"flush_interval" yaml:"flush_interval" binding:"required,gt=0"`
+	WriteTimeout  time.Duration `mapstructure:"write_timeout" yaml:"write_timeout" binding:"required,gt=0"`
+	FallbackFile  string        `mapstructure:"fallback_file" yaml:"fallback_file"`
+}
+
+// MongoRetentionConfig bounds the logs collection. MongoDB removes entries older than TTL by
+// itself, about once a minute; when the collection still grows beyond MaxSizeMB, checked
+// every CheckInterval, the oldest entries are deleted. MaxSizeMB 0 turns the size limit off.
+type MongoRetentionConfig struct {
+	TTL           time.Duration `mapstructure:"ttl" yaml:"ttl" binding:"required,min=1s"`
+	MaxSizeMB     int           `mapstructure:"max_size_mb" yaml:"max_size_mb" binding:"min=0"`
+	CheckInterval time.Duration `mapstructure:"check_interval" yaml:"check_interval" binding:"required,gt=0"`
 }
 
-func NewConfig() (Config, error) {
-	err := initConfig()
-	if err != nil {
-		return Config{}, err
+func (c MongoConfig) URL() string {
+	return fmt.Sprintf("mongodb://%s", net.JoinHostPort(c.Host, c.Port))
+}
+
+type AuthConfig struct {
+	AccessTokenTTL  time.Duration        `mapstructure:"access_token_ttl" yaml:"access_token_ttl" binding:"required,gt=0"`
+	RefreshTokenTTL time.Duration        `mapstructure:"refresh_token_ttl" yaml:"refresh_token_ttl" binding:"required,gt=0"`
+	PasswordHasher  string               `mapstructure:"password_hasher" yaml:"password_hasher" binding:"required,oneof=argon2id bcrypt"`
+	LegacySalt      string               `mapstructure:"legacy_salt" yaml:"legacy_salt"`
+	ActiveKey       string               `mapstructure:"active_key" yaml:"active_key" binding:"required"`
+	Keys            []SigningKeyConfig   `mapstructure:"keys" yaml:"keys" binding:"required,min=1,dive"`
+	PasswordPolicy  PasswordPolicyConfig `mapstructure:"password_policy" yaml:"password_policy"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength     int  `mapstructure:"min_length" yaml:"min_length" binding:"min=1"`
+	MaxLength     int  `mapstructure:"max_length" yaml:"max_length" binding:"gtefield=MinLength"`
+	RequireUpper  bool `mapstructure:"require_upper" yaml:"require_upper"`
+	RequireLower  bool `mapstructure:"require_lower" yaml:"require_lower"`
+	RequireDigit  bool `mapstructure:"require_digit" yaml:"require_digit"`
+	RequireSymbol bool `mapstructure:"require_symbol" yaml:"require_symbol"`
+}
+
+// ReminderConfig is checked only while Enabled, except that nothing may be negative.
+type ReminderConfig struct {
+	Enabled   bool          `mapstructure:"enabled" yaml:"enabled"`
+	Interval  time.Duration `mapstructure:"interval" yaml:"interval" binding:"required_if=Enabled true,gte=0"`
+	LeadTime  time.Duration `mapstructure:"lead_time" yaml:"lead_time" binding:"required_if=Enabled true,gte=0"`
+	BatchSize int           `mapstructure:"batch_size" yaml:"batch_size" binding:"required_if=Enabled true,gte=0"`
+}
+
+// MetricsConfig protects /metrics, which scrapers call with "Authorization: Bearer <Token>";
+// while Token is empty the endpoint rejects every request. The active users gauge is counted
+// in Postgres every ActiveUsersInterval rather than on every scrape.
+type MetricsConfig struct {
+	Token               string        `mapstructure:"token" yaml:"token"`
+	ActiveUsersInterval time.Duration `mapstructure:"active_users_interval" yaml:"active_users_interval" binding:"required,gt=0"`
+}
+
+// LogConfig picks the sinks logs are written to. Level applies to all of them and is the only
+// key reloaded at runtime; a sink with a level of its own drops the entries below it, so sinks
+// can be quieter than Level but not noisier.
+type LogConfig struct {
+	Level    string            `mapstructure:"level" yaml:"level" binding:"required,oneof=debug info warn error"`
+	Sampling LogSamplingConfig `mapstructure:"sampling" yaml:"sampling"`
+	Console  LogSinkConfig     `mapstructure:"console" yaml:"console"`
+	JSON     LogSinkConfig     `mapstructure:"json" yaml:"json"`
+	File     LogFileConfig     `mapstructure:"file" yaml:"file"`
+	Mongo    LogSinkConfig     `mapstructure:"mongo" yaml:"mongo"`
+}
+
+// LogSamplingConfig keeps the first Initial entries with the same level and message every
+// second and then every Thereafter-th of them. Thereafter 0 turns sampling off.
+type LogSamplingConfig struct {
+	Initial    int `mapstructure:"initial" yaml:"initial" binding:"min=0"`
+	Thereafter int `mapstructure:"thereafter" yaml:"thereafter" binding:"min=0"`
+}
+
+type LogSinkConfig struct {
+	Enabled bool   `mapstructure:"enabled" yaml:"enabled"`
+	Level   string `mapstructure:"level" yaml:"level" binding:"omitempty,oneof=debug info warn error"`
+}
+
+// LogFileConfig writes JSON lines to Path, rotated once it grows to MaxSizeMB.
+type LogFileConfig struct {
+	Enabled    bool   `mapstructure:"enabled" yaml:"enabled"`
+	Level      string `mapstructure:"level" yaml:"level" binding:"omitempty,oneof=debug info warn error"`
+	Path       string `mapstructure:"path" yaml:"path" binding:"required_if=Enabled true"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb" yaml:"max_size_mb" binding:"min=0"`
+	MaxBackups int    `mapstructure:"max_backups" yaml:"max_backups" binding:"min=0"`
+	MaxAgeDays int    `mapstructure:"max_age_days" yaml:"max_age_days" binding:"min=0"`
+	Compress   bool   `mapstructure:"compress" yaml:"compress"`
+}
+
+type CacheConfig struct {
+	TTL time.Duration `mapstructure:"ttl" yaml:"ttl" binding:"required,gt=0"`
+}
+
+// RateLimitConfig limits the requests of every client IP with a token bucket refilled at
+// RequestsPerSecond and holding up to Burst requests.
+type RateLimitConfig struct {
+	Enabled           bool    `mapstructure:"enabled" yaml:"enabled"`
+	RequestsPerSecond float64 `mapstructure:"requests_per_second" yaml:"requests_per_second" binding:"required_if=Enabled true,gte=0"`
+	Burst             int     `mapstructure:"burst" yaml:"burst" binding:"required_if=Enabled true,gte=0"`
+}
+
+// CORSConfig lists the origins browsers may call the API from; "*" allows any origin.
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins" yaml:"allowed_origins" binding:"dive,required"`
+}
+
+type FeatureFlags struct {
+	Search  bool `mapstructure:"search" yaml:"search"`
+	Swagger bool `mapstructure:"swagger" yaml:"swagger"`
+	Metrics bool `mapstructure:"metrics" yaml:"metrics"`
+}
+
+// SigningKeyConfig describes one JWT key. HS256 keys take their secret either inline or from
+// the environment variable named by SecretEnv; RS256 and EdDSA keys are read from PEM files.
+// A key without a private key file can only verify tokens.
+type SigningKeyConfig struct {
+	Id             string `mapstructure:"kid" yaml:"kid" binding:"required"`
+	Algorithm      string `mapstructure:"algorithm" yaml:"algorithm" binding:"required,oneof=HS256 RS256 EdDSA"`
+	Secret         string `mapstructure:"secret" yaml:"secret,omitempty"`
+	SecretEnv      string `mapstructure:"secret_env" yaml:"secret_env,omitempty"`
+	PrivateKeyFile string `mapstructure:"private_key_file" yaml:"private_key_file,omitempty"`
+	PublicKeyFile  string `mapstructure:"public_key_file" yaml:"public_key_file,omitempty"`
+	Retired        bool   `mapstructure:"retired" yaml:"retired,omitempty"`
+}
+
+const redacted = "[redacted]"
+
+// Redacted returns a copy of the config that is safe to print: passwords, secrets, tokens and
+// the legacy salt are replaced by a placeholder when they are set.
+func (c Config) Redacted() Config {
+	redact := func(s *string) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+
+	redact(&c.Postgres.Password)
+	redact(&c.Redis.Password)
+	redact(&c.Auth.LegacySalt)
+	redact(&c.Metrics.Token)
+
+	keys := make([]SigningKeyConfig, len(c.Auth.Keys))
+	copy(keys, c.Auth.Keys)
+	for i := range keys {
+		redact(&keys[i].Secret)
 	}
+	c.Auth.Keys = keys
 
-	return Config{
-		Postgres: PostgresConfig{
-			Host:     viper.GetString("postgres.host"),
-			Port:     viper.GetString("postgres.port"),
-			Username: viper.GetString("postgres.username"),
-			DBName:   viper.GetString("postgres.dbname"),
-			SSLMode:  viper.GetString("postgres.sslmode"),
-			Password: os.Getenv("POSTGRES_PASSWORD"),
-		},
-		Redis: RedisConfig{
-			Address:  fmt.Sprintf("%s:%s", viper.GetString("redis.host"), viper.GetString("redis.port")),
-			Password: viper.GetString("redis.password"),
-			DB:       viper.GetInt("redis.db"),
-		},
-		Mongo: MongoConfig{
-			DB:       viper.GetString("mongo.dbname"),
-			MongoURL: fmt.Sprintf("mongodb://%s:%s", viper.GetString("mongo.host"), viper.GetString("mongo.port")),
-		},
-	}, nil
-}
-
-func initConfig() error {
-	viper.AddConfigPath("config")
-	viper.SetConfigName("config")
-	return viper.ReadInConfig()
+	return c
 }
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix prefixes the environment variable of every key: postgres.host is read from
+// TODO_POSTGRES_HOST, auth.password_policy.min_length from TODO_AUTH_PASSWORD_POLICY_MIN_LENGTH.
+const EnvPrefix = "TODO"
+
+const (
+	defaultDir  = "config"
+	defaultFile = "config.yml"
+	profileEnv  = EnvPrefix + "_PROFILE"
+)
+
+var profiles = []string{"dev", "test", "prod"}
+
+// legacyEnv keeps the variables set by older docker-compose files working. The prefixed
+// variable wins when both are set.
+var legacyEnv = map[string]string{
+	"postgres.host":     "POSTGRES_HOST",
+	"postgres.username": "POSTGRES_USER",
+	"postgres.password": "POSTGRES_PASSWORD",
+	"postgres.dbname":   "POSTGRES_DB",
+	"redis.host":        "REDIS_HOST",
+	"redis.port":        "REDIS_PORT",
+	"mongo.host":        "MONGO_HOST",
+	"mongo.port":        "MONGO_PORT",
+	"mongo.dbname":      "MONGO_DATABASE",
+}
+
+// Options select where a config is read from, in increasing order of precedence: the base
+// file, the profile file next to it, Files, environment variables and Overrides.
+type Options struct {
+	// File is the base file, config/config.yml by default.
+	File string
+	// Profile merges config.<profile>.yml from the directory of the base file. It defaults to
+	// the TODO_PROFILE variable; empty means no profile.
+	Profile string
+	// Files are merged over the base and profile files in order.
+	Files []string
+	// Overrides are key=value pairs, e.g. server.port=9000.
+	Overrides []string
+}
+
+// NewConfig reads and validates the config. Unknown keys, missing required keys and
+// invalid values are reported all at once.
+func NewConfig(opts Options) (Config, error) {
+	v := viper.New()
+
+	if err := readFiles(v, opts); err != nil {
+		return Config{}, err
+	}
+
+	bindEnv(v, reflect.TypeOf(Config{}), "")
+
+	for _, override := range opts.Overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok || key == "" {
+			return Config{}, fmt.Errorf("override %q is not key=value", override)
+		}
+		v.Set(key, value)
+	}
+
+	var cfg Config
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	for i, key := range cfg.Auth.Keys {
+		if key.Secret == "" && key.SecretEnv != "" {
+			cfg.Auth.Keys[i].Secret = os.Getenv(key.SecretEnv)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func readFiles(v *viper.Viper, opts Options) error {
+	files, err := opts.files()
+	if err != nil {
+		return err
+	}
+
+	v.SetConfigFile(files[0])
+	if err = v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", files[0], err)
+	}
+
+	for _, f := range files[1:] {
+		v.SetConfigFile(f)
+		if err = v.MergeInConfig(); err != nil {
+			return fmt.Errorf("failed to read %s: %w", f, err)
+		}
+	}
+
+	return nil
+}
+
+// files lists the files to read, the base file first.
+func (o Options) files() ([]string, error) {
+	base := o.File
+	if base == "" {
+		base = filepath.Join(defaultDir, defaultFile)
+	}
+	files := []string{base}
+
+	profile := o.Profile
+	if profile == "" {
+		profile = os.Getenv(profileEnv)
+	}
+
+	if profile != "" {
+		if !contains(profiles, profile) {
+			return nil, fmt.Errorf("unknown profile %q, expected one of %s", profile, strings.Join(profiles, ", "))
+		}
+
+		ext := filepath.Ext(base)
+		files = append(files, strings.TrimSuffix(base, ext)+"."+profile+ext)
+	}
+
+	return append(files, o.Files...), nil
+}
+
+// bindEnv registers the variable of every key of the struct, so keys missing from the files
+// can still be set from the environment. Lists cannot be set this way.
+func bindEnv(v *viper.Viper, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+
+		if field.Type.Kind() == reflect.Struct && field.Type.PkgPath() == t.PkgPath() {
+			bindEnv(v, field.Type, key+".")
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice {
+			continue
+		}
+
+		names := []string{key, envName(key)}
+		if legacy, ok := legacyEnv[key]; ok {
+			names = append(names, legacy)
+		}
+		_ = v.BindEnv(names...)
+	}
+}
+
+func envName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.SetTagName("binding")
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("mapstructure")
+	})
+
+	return v
+}
+
+// Validate checks the binding tags and the references between keys.
+func (c Config) Validate() error {
+	var problems []string
+
+	var fieldErrs validator.ValidationErrors
+	if err := validate.Struct(c); errors.As(err, &fieldErrs) {
+		for _, e := range fieldErrs {
+			problems = append(problems, describe(e))
+		}
+	} else if err != nil {
+		return err
+	}
+
+	activeKey := false
+	for _, key := range c.Auth.Keys {
+		activeKey = activeKey || key.Id == c.Auth.ActiveKey
+	}
+	if c.Auth.ActiveKey != "" && !activeKey {
+		problems = append(problems, fmt.Sprintf("auth.active_key: no key with kid %q", c.Auth.ActiveKey))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// describe names the key of a failed check the way it is written in the files.
+func describe(e validator.FieldError) string {
+	key := strings.TrimPrefix(e.Namespace(), "Config.")
+
+	switch e.Tag() {
+	case "required", "required_if":
+		if strings.Contains(key, "[") {
+			return fmt.Sprintf("%s: is required", key)
+		}
+		return fmt.Sprintf("%s: is required (env %s)", key, envName(key))
+	case "oneof":
+		return fmt.Sprintf("%s: must be one of %s, got %v", key, e.Param(), e.Value())
+	case "gt":
+		return fmt.Sprintf("%s: must be greater than %s, got %v", key, e.Param(), e.Value())
+	case "gte":
+		return fmt.Sprintf("%s: must not be less than %s, got %v", key, e.Param(), e.Value())
+	case "ltfield":
+		return fmt.Sprintf("%s: must be less than %s", key, snakeCase(e.Param()))
+	case "gtefield":
+		return fmt.Sprintf("%s: must not be less than %s", key, snakeCase(e.Param()))
+	default:
+		return fmt.Sprintf("%s: failed %s=%s, got %v", key, e.Tag(), e.Param(), e.Value())
+	}
+}
+
+// snakeCase turns the Go name of a field referenced by a check into its key, e.g.
+// WriteTimeout into write_timeout.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
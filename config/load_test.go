@@ -0,0 +1,237 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile writes a config file to a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+
+	return path
+}
+
+func TestNewConfigPrecedence(t *testing.T) {
+	overlay := writeFile(t, "overlay.yml", "postgres:\n  host: overlay\nserver:\n  port: \"7000\"\n")
+
+	tests := []struct {
+		name  string
+		opts  Options
+		env   map[string]string
+		check func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "base file",
+			opts: Options{File: defaultFile},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Postgres.Host != "postgres" || cfg.Log.Level != "info" {
+					t.Fatalf("postgres.host %q, log.level %q", cfg.Postgres.Host, cfg.Log.Level)
+				}
+			},
+		},
+		{
+			name: "profile over base",
+			opts: Options{File: defaultFile, Profile: "dev"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Postgres.Host != "localhost" || cfg.Log.Level != "debug" || cfg.Reminders.Interval != 10*time.Second {
+					t.Fatalf("postgres.host %q, log.level %q, reminders.interval %s", cfg.Postgres.Host, cfg.Log.Level, cfg.Reminders.Interval)
+				}
+				// keys the profile does not set come from the base file
+				if cfg.Server.Port != "8000" {
+					t.Fatalf("server.port %q", cfg.Server.Port)
+				}
+			},
+		},
+		{
+			name: "profile from the environment",
+			opts: Options{File: defaultFile},
+			env:  map[string]string{profileEnv: "dev"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Postgres.Host != "localhost" {
+					t.Fatalf("postgres.host %q", cfg.Postgres.Host)
+				}
+			},
+		},
+		{
+			name: "overlay over profile",
+			opts: Options{File: defaultFile, Profile: "dev", Files: []string{overlay}},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Postgres.Host != "overlay" || cfg.Server.Port != "7000" || cfg.Log.Level != "debug" {
+					t.Fatalf("postgres.host %q, server.port %q, log.level %q", cfg.Postgres.Host, cfg.Server.Port, cfg.Log.Level)
+				}
+			},
+		},
+		{
+			name: "legacy env over files",
+			opts: Options{File: defaultFile, Files: []string{overlay}},
+			env:  map[string]string{"POSTGRES_HOST": "legacy"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Postgres.Host != "legacy" {
+					t.Fatalf("postgres.host %q", cfg.Postgres.Host)
+				}
+			},
+		},
+		{
+			name: "prefixed env over legacy env",
+			opts: Options{File: defaultFile},
+			env:  map[string]string{"POSTGRES_HOST": "legacy", "TODO_POSTGRES_HOST": "prefixed"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Postgres.Host != "prefixed" {
+					t.Fatalf("postgres.host %q", cfg.Postgres.Host)
+				}
+			},
+		},
+		{
+			name: "env sets nested keys",
+			opts: Options{File: defaultFile},
+			env:  map[string]string{"TODO_AUTH_PASSWORD_POLICY_MIN_LENGTH": "12"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Auth.PasswordPolicy.MinLength != 12 {
+					t.Fatalf("auth.password_policy.min_length %d", cfg.Auth.PasswordPolicy.MinLength)
+				}
+			},
+		},
+		{
+			name: "overrides over env",
+			opts: Options{File: defaultFile, Overrides: []string{"postgres.host=flag", "server.port=9000"}},
+			env:  map[string]string{"TODO_POSTGRES_HOST": "prefixed", "TODO_SERVER_PORT": "8080"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Postgres.Host != "flag" || cfg.Server.Port != "9000" {
+					t.Fatalf("postgres.host %q, server.port %q", cfg.Postgres.Host, cfg.Server.Port)
+				}
+			},
+		},
+		{
+			name: "secret from the named variable",
+			opts: Options{File: defaultFile},
+			env:  map[string]string{"JWT_SECRET": "from-env"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Auth.Keys[0].Secret != "from-env" {
+					t.Fatalf("secret %q", cfg.Auth.Keys[0].Secret)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := NewConfig(tt.opts)
+			if err != nil {
+				t.Fatalf("NewConfig: %v", err)
+			}
+
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestNewConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "unknown profile",
+			opts: Options{File: defaultFile, Profile: "staging"},
+			want: []string{`unknown profile "staging"`},
+		},
+		{
+			name: "missing file",
+			opts: Options{File: defaultFile, Files: []string{"missing.yml"}},
+			want: []string{"failed to read missing.yml"},
+		},
+		{
+			name: "malformed override",
+			opts: Options{File: defaultFile, Overrides: []string{"server.port"}},
+			want: []string{`override "server.port" is not key=value`},
+		},
+		{
+			name: "unknown key",
+			opts: Options{File: defaultFile, Files: []string{writeFile(t, "typo.yml", "server:\n  prot: \"9000\"\n")}},
+			want: []string{"failed to decode config", "prot"},
+		},
+		{
+			name: "missing required key",
+			opts: Options{File: defaultFile, Overrides: []string{"postgres.host="}},
+			want: []string{"postgres.host: is required (env TODO_POSTGRES_HOST)"},
+		},
+		{
+			name: "value outside oneof",
+			opts: Options{File: defaultFile, Overrides: []string{"redis.codec=gob"}},
+			want: []string{"redis.codec: must be one of json msgpack, got gob"},
+		},
+		{
+			name: "negative durations",
+			opts: Options{File: defaultFile, Overrides: []string{"cache.ttl=-1m", "auth.access_token_ttl=-15m", "reminders.interval=-1s"}},
+			want: []string{
+				"cache.ttl: must be greater than 0, got -1m0s",
+				"auth.access_token_ttl: must be greater than 0, got -15m0s",
+				"reminders.interval: must not be less than 0, got -1s",
+			},
+		},
+		{
+			name: "negative retention check interval",
+			opts: Options{File: defaultFile, Overrides: []string{"mongo.retention.check_interval=-5m"}},
+			want: []string{"mongo.retention.check_interval: must be greater than 0, got -5m0s"},
+		},
+		{
+			name: "zero active users interval",
+			opts: Options{File: defaultFile, Overrides: []string{"metrics.active_users_interval=0s"}},
+			want: []string{"metrics.active_users_interval: is required"},
+		},
+		{
+			name: "zero interval of enabled reminders",
+			opts: Options{File: defaultFile, Overrides: []string{"reminders.interval=0s"}},
+			want: []string{"reminders.interval: is required"},
+		},
+		{
+			name: "deadline beyond write timeout",
+			opts: Options{File: defaultFile, Overrides: []string{"server.request_timeout=30s"}},
+			want: []string{"server.request_timeout: must be less than write_timeout"},
+		},
+		{
+			name: "unknown active key",
+			opts: Options{File: defaultFile, Overrides: []string{"auth.active_key=missing"}},
+			want: []string{`auth.active_key: no key with kid "missing"`},
+		},
+		{
+			name: "all problems at once",
+			opts: Options{File: defaultFile, Overrides: []string{"postgres.host=", "redis.codec=gob"}},
+			want: []string{"postgres.host: is required", "redis.codec: must be one of"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := NewConfig(tt.opts)
+			if err == nil {
+				t.Fatal("no error")
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
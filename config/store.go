@@ -0,0 +1,226 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// reloadable are the keys applied by Reload, with everything under them. Changes to any
+// other key are reported but need a restart.
+var reloadable = map[string]bool{
+	"log.level":  true,
+	"cache":      true,
+	"rate_limit": true,
+	"cors":       true,
+	"features":   true,
+}
+
+// secrets are reported as changed without their values.
+var secrets = map[string]bool{
+	"postgres.password": true,
+	"redis.password":    true,
+	"auth.legacy_salt":  true,
+	"auth.keys":         true,
+	"metrics.token":     true,
+}
+
+// reloadDebounce collapses the burst of events editors and config management tools cause
+// when they save a file.
+const reloadDebounce = 250 * time.Millisecond
+
+// Store holds the active config. Readers get it with Current, which is safe to call from
+// any goroutine; Reload swaps it atomically.
+type Store struct {
+	opts     Options
+	current  atomic.Pointer[Config]
+	mu       sync.Mutex
+	handlers []func(Config)
+}
+
+// NewStore makes cfg the active config. opts are used to read it again on Reload.
+func NewStore(cfg Config, opts Options) *Store {
+	s := &Store{opts: opts}
+	s.current.Store(&cfg)
+	return s
+}
+
+// Current returns the active config. It must be treated as read-only.
+func (s *Store) Current() Config {
+	return *s.current.Load()
+}
+
+// OnChange registers fn to be called with the new config after every applied reload.
+func (s *Store) OnChange(fn func(Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers = append(s.handlers, fn)
+}
+
+// ReloadResult lists the keys a reload changed, as "key: old -> new", and the changed keys
+// it ignored because they need a restart.
+type ReloadResult struct {
+	Changed []string
+	Ignored []string
+}
+
+// Reload reads and validates the config again and applies the reloadable sections of it.
+// An invalid config is rejected as a whole and the active one stays.
+func (s *Store) Reload() (ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fresh, err := NewConfig(s.opts)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	current := s.Current()
+	next := current
+
+	applyReloadable(reflect.ValueOf(&next).Elem(), reflect.ValueOf(fresh), "")
+
+	result := ReloadResult{
+		Changed: diff(current, next),
+		Ignored: diff(next, fresh),
+	}
+
+	if len(result.Changed) == 0 {
+		return result, nil
+	}
+
+	s.current.Store(&next)
+	for _, handler := range s.handlers {
+		handler(next)
+	}
+
+	return result, nil
+}
+
+// Watch reloads the config on SIGHUP and whenever one of its files changes, logging what
+// every reload changed, until ctx is done.
+func (s *Store) Watch(ctx context.Context, logger *zap.SugaredLogger) error {
+	files, err := s.opts.files()
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// directories are watched rather than the files, which editors and Kubernetes config
+	// maps replace instead of writing to
+	watched := make(map[string]bool, len(files))
+	for _, f := range files {
+		path, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		watched[path] = true
+
+		if err = watcher.Add(filepath.Dir(path)); err != nil {
+			return err
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			s.reloadAndLog(logger, "SIGHUP")
+		case event := <-watcher.Events:
+			path, _ := filepath.Abs(event.Name)
+			if watched[path] && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				debounce.Reset(reloadDebounce)
+			}
+		case <-debounce.C:
+			s.reloadAndLog(logger, "file change")
+		case err := <-watcher.Errors:
+			logger.Warnw("config watcher failed", "error", err)
+		}
+	}
+}
+
+func (s *Store) reloadAndLog(logger *zap.SugaredLogger, trigger string) {
+	result, err := s.Reload()
+	if err != nil {
+		logger.Errorw("config reload rejected, the active config stays", "trigger", trigger, "error", err)
+		return
+	}
+
+	if len(result.Changed) > 0 {
+		logger.Infow("config reloaded", "trigger", trigger, "changes", result.Changed)
+	} else {
+		logger.Infow("config reloaded without changes", "trigger", trigger)
+	}
+
+	if len(result.Ignored) > 0 {
+		logger.Warnw("config changes need a restart to apply", "trigger", trigger, "changes", result.Ignored)
+	}
+}
+
+// applyReloadable copies the reloadable keys from fresh into next.
+func applyReloadable(next, fresh reflect.Value, prefix string) {
+	for i := 0; i < next.NumField(); i++ {
+		field := next.Type().Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+
+		switch {
+		case reloadable[key]:
+			next.Field(i).Set(fresh.Field(i))
+		case field.Type.Kind() == reflect.Struct && field.Type.PkgPath() == next.Type().PkgPath():
+			applyReloadable(next.Field(i), fresh.Field(i), key+".")
+		}
+	}
+}
+
+// diff lists the keys whose values differ between a and b.
+func diff(a, b Config) []string {
+	var changes []string
+	diffValues(reflect.ValueOf(a), reflect.ValueOf(b), "", &changes)
+	return changes
+}
+
+func diffValues(a, b reflect.Value, prefix string, changes *[]string) {
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		av, bv := a.Field(i), b.Field(i)
+
+		if field.Type.Kind() == reflect.Struct && field.Type.PkgPath() == a.Type().PkgPath() {
+			diffValues(av, bv, key+".", changes)
+			continue
+		}
+
+		if reflect.DeepEqual(av.Interface(), bv.Interface()) {
+			continue
+		}
+
+		if secrets[key] {
+			*changes = append(*changes, key+": changed")
+		} else {
+			*changes = append(*changes, fmt.Sprintf("%s: %v -> %v", key, av.Interface(), bv.Interface()))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreReload(t *testing.T) {
+	overlay := writeFile(t, "overlay.yml", "postgres:\n  password: old-secret\n")
+	opts := Options{File: defaultFile, Files: []string{overlay}}
+
+	cfg, err := NewConfig(opts)
+	if err != nil {
+		t.Fatalf("NewConfig: %v", err)
+	}
+
+	store := NewStore(cfg, opts)
+
+	var notified []Config
+	store.OnChange(func(cfg Config) { notified = append(notified, cfg) })
+
+	rewrite := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(overlay, []byte(content), 0o600); err != nil {
+			t.Fatalf("rewrite overlay: %v", err)
+		}
+	}
+
+	rewrite("postgres:\n  password: new-secret\nserver:\n  port: \"9000\"\nlog:\n  level: debug\ncache:\n  ttl: 1m\n")
+
+	result, err := store.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	changed := strings.Join(result.Changed, "\n")
+	for _, want := range []string{"log.level: info -> debug", "cache.ttl: "} {
+		if !strings.Contains(changed, want) {
+			t.Errorf("changes %q do not contain %q", result.Changed, want)
+		}
+	}
+
+	ignored := strings.Join(result.Ignored, "\n")
+	for _, want := range []string{"server.port: 8000 -> 9000", "postgres.password: changed"} {
+		if !strings.Contains(ignored, want) {
+			t.Errorf("ignored changes %q do not contain %q", result.Ignored, want)
+		}
+	}
+	if strings.Contains(changed+ignored, "secret") {
+		t.Errorf("a secret is shown: %q %q", result.Changed, result.Ignored)
+	}
+
+	current := store.Current()
+	if current.Log.Level != "debug" || current.Cache.TTL != time.Minute {
+		t.Errorf("reloadable keys not applied: log.level %q, cache.ttl %s", current.Log.Level, current.Cache.TTL)
+	}
+	if current.Server.Port != "8000" || current.Postgres.Password != "old-secret" {
+		t.Errorf("keys needing a restart applied: server.port %q, postgres.password %q", current.Server.Port, current.Postgres.Password)
+	}
+	if len(notified) != 1 || notified[0].Log.Level != "debug" {
+		t.Errorf("handlers got %d configs", len(notified))
+	}
+
+	t.Run("invalid config is rejected", func(t *testing.T) {
+		rewrite("log:\n  level: verbose\ncache:\n  ttl: 5m\n")
+
+		if _, err := store.Reload(); err == nil || !strings.Contains(err.Error(), "log.level") {
+			t.Fatalf("Reload: %v", err)
+		}
+
+		if current := store.Current(); current.Log.Level != "debug" || current.Cache.TTL != time.Minute {
+			t.Fatalf("active config changed: log.level %q, cache.ttl %s", current.Log.Level, current.Cache.TTL)
+		}
+		if len(notified) != 1 {
+			t.Fatalf("handlers called for a rejected config")
+		}
+	})
+}
+
+func TestDiffRedactsSecrets(t *testing.T) {
+	a := Config{Auth: AuthConfig{Keys: []SigningKeyConfig{{Id: "k1", Secret: "first"}}, LegacySalt: "salt-1"}}
+	b := Config{Auth: AuthConfig{Keys: []SigningKeyConfig{{Id: "k1", Secret: "second"}}, LegacySalt: "salt-2"}}
+	b.Redis.Password = "redis-secret"
+
+	changes := diff(a, b)
+	want := []string{"redis.password: changed", "auth.legacy_salt: changed", "auth.keys: changed"}
+	if strings.Join(changes, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("changes %q, want %q", changes, want)
+	}
+}
@@ -0,0 +1,43 @@
+package testenv
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"time"
+)
+
+// Config mirrors config/config.yml with the background jobs and the rate limit switched off.
+func Config() config.Config {
+	return config.Config{
+		Server: config.ServerConfig{
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			MaxHeaderBytes:  1 << 20,
+			AuthTimeout:     5 * time.Second,
+			RequestTimeout:  3 * time.Second,
+			SearchTimeout:   2 * time.Second,
+			ShutdownTimeout: 5 * time.Second,
+		},
+		Redis: config.RedisConfig{Codec: "json"},
+		Auth: config.AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: time.Hour,
+			PasswordHasher:  "bcrypt",
+			LegacySalt:      "test-salt",
+			ActiveKey:       "test",
+			Keys: []config.SigningKeyConfig{
+				{Id: "test", Algorithm: "HS256", Secret: "test-secret"},
+			},
+			PasswordPolicy: config.PasswordPolicyConfig{
+				MinLength:    8,
+				MaxLength:    128,
+				RequireUpper: true,
+				RequireLower: true,
+				RequireDigit: true,
+			},
+		},
+		Metrics:  config.MetricsConfig{Token: "test-metrics-token", ActiveUsersInterval: time.Minute},
+		Log:      config.LogConfig{Level: "debug"},
+		Cache:    config.CacheConfig{TTL: 10 * time.Minute},
+		Features: config.FeatureFlags{Search: true, Swagger: true, Metrics: true},
+	}
+}
@@ -0,0 +1,177 @@
+// Package testenv provides disposable stand-ins for the services the API depends on:
+// a migrated Postgres schema, miniredis in place of Redis and an in-memory zap core in
+// place of the MongoDB log sink.
+//
+// Postgres is the database named by TEST_POSTGRES_DSN or, without it, an embedded server
+// started on first use. Packages using Postgres must run their tests through Main, which
+// stops the embedded server. Tests needing Postgres are skipped when neither is available,
+// except under CI=true, where a missing database must not pass as a green build.
+package testenv
+
+import (
+	"context"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/migration"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/go-redis/redis/v8"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	startOnce sync.Once
+	dsn       string
+	startErr  error
+	embedded  *embeddedpostgres.EmbeddedPostgres
+)
+
+// Main runs the tests of a package and stops the embedded Postgres afterwards.
+func Main(m *testing.M) {
+	code := m.Run()
+
+	if embedded != nil {
+		embedded.Stop()
+	}
+
+	os.Exit(code)
+}
+
+func startPostgres() {
+	if dsn = os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		return
+	}
+
+	port, err := freePort()
+	if err != nil {
+		startErr = err
+		return
+	}
+
+	runtimePath, err := os.MkdirTemp("", "todo-postgres-")
+	if err != nil {
+		startErr = err
+		return
+	}
+
+	db := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(uint32(port)).
+		RuntimePath(runtimePath).
+		StartTimeout(time.Minute).
+		Logger(nil))
+	if err = db.Start(); err != nil {
+		startErr = fmt.Errorf("embedded postgres: %w", err)
+		return
+	}
+
+	embedded = db
+	dsn = fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=postgres sslmode=disable", port)
+}
+
+// Postgres returns a connection to a fresh schema with every embedded migration applied.
+// The schema is dropped when the test ends.
+func Postgres(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	startOnce.Do(startPostgres)
+	if startErr != nil {
+		if os.Getenv("CI") == "true" {
+			t.Fatalf("postgres is not available, set TEST_POSTGRES_DSN: %v", startErr)
+		}
+		t.Skipf("postgres is not available, set TEST_POSTGRES_DSN: %v", startErr)
+	}
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	admin.MustExec("CREATE SCHEMA " + schema)
+	t.Cleanup(func() {
+		admin.MustExec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	db, err := sqlx.Connect("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrate(t, db)
+
+	return db
+}
+
+func migrate(t *testing.T, db *sqlx.DB) {
+	t.Helper()
+
+	migrator, err := migration.NewMigrator(context.Background(), db)
+	if err != nil {
+		t.Fatalf("migrator: %v", err)
+	}
+	defer migrator.Close()
+
+	if err = migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withSearchPath makes every connection of the pool use the schema.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema
+	}
+
+	return dsn + " search_path=" + schema
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Redis returns a client of an in-process Redis server, closed when the test ends.
+func Redis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return client, server
+}
+
+// Logger returns a logger keeping its entries in memory, and installs it as the global
+// logger for the duration of the test.
+func Logger(t *testing.T) (*zap.SugaredLogger, *observer.ObservedLogs) {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+
+	restore := zap.ReplaceGlobals(logger)
+	t.Cleanup(restore)
+
+	return logger.Sugar(), logs
+}
@@ -0,0 +1,657 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/internal/testenv"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/middlewares"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/cache"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/logs"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const password = "Secret123"
+
+func TestMain(m *testing.M) {
+	testenv.Main(m)
+}
+
+// env is the whole API wired the way cmd/main.go wires it, on top of the stand-ins.
+type env struct {
+	handler  http.Handler
+	services *service.Service
+	level    zap.AtomicLevel
+	redis    *miniredis.Miniredis
+	logs     *observer.ObservedLogs
+}
+
+func newEnv(t *testing.T) *env {
+	t.Helper()
+
+	postgres := testenv.Postgres(t)
+	redisClient, redisServer := testenv.Redis(t)
+	logger, observed := testenv.Logger(t)
+
+	cfg := testenv.Config()
+	services, err := service.NewService(cfg, postgres, redisClient, logger)
+	if err != nil {
+		t.Fatalf("init services: %v", err)
+	}
+
+	srv := api.NewServer(config.NewStore(cfg, config.Options{}), middlewares.NewUserAuthMiddleware(services.AuthService))
+	srv.HandleHealth(func(ctx context.Context) error { return postgres.PingContext(ctx) })
+	srv.HandleAuth(services.AuthService)
+	srv.HandleLists(services.ListService)
+	srv.HandleItems(services.ItemService)
+	srv.HandleSearch(services.SearchService)
+
+	level := zap.NewAtomicLevelAt(zap.DebugLevel)
+	srv.HandleLogLevel(level)
+	srv.HandleLogs(logs.NewLogService(logRepository{}))
+
+	return &env{
+		handler:  srv.Handler(),
+		services: services,
+		level:    level,
+		redis:    redisServer,
+		logs:     observed,
+	}
+}
+
+// client sends requests as one user. An empty token sends them anonymously.
+type client struct {
+	env    *env
+	tokens todo.Tokens
+}
+
+func (e *env) anonymous() *client {
+	return &client{env: e}
+}
+
+// signUp registers the user and signs them in.
+func (e *env) signUp(t *testing.T, username string) *client {
+	t.Helper()
+
+	c := e.anonymous()
+	c.mustDo(t, http.MethodPost, "/auth/sign-up/", todo.SignUpInput{Name: username, Username: username, Password: password}, http.StatusOK, nil)
+	c.mustDo(t, http.MethodPost, "/auth/sign-in/", map[string]string{"username": username, "password": password}, http.StatusOK, &c.tokens)
+
+	return c
+}
+
+// do sends the request and decodes a successful response into out. Failures are reported
+// to t, which must be the subtest when called from one: failing its parent panics.
+func (c *client) do(t *testing.T, method, path string, body, out interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+
+	r := httptest.NewRequest(method, path, &reader)
+	r.Header.Set(utility.ContentType, utility.ApplicationJSON)
+	if c.tokens.AccessToken != "" {
+		r.Header.Set("Authorization", "Bearer "+c.tokens.AccessToken)
+	}
+
+	w := httptest.NewRecorder()
+	c.env.handler.ServeHTTP(w, r)
+
+	if out != nil && w.Code < http.StatusBadRequest {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+
+	return w
+}
+
+func (c *client) mustDo(t *testing.T, method, path string, body interface{}, status int, out interface{}) {
+	t.Helper()
+
+	if w := c.do(t, method, path, body, out); w.Code != status {
+		t.Fatalf("%s %s: got %d, want %d: %s", method, path, w.Code, status, w.Body.String())
+	}
+}
+
+// problem sends a request expected to fail with the status and error code.
+func (c *client) problem(t *testing.T, method, path string, body interface{}, status int, code string) utility.Problem {
+	t.Helper()
+
+	w := c.do(t, method, path, body, nil)
+	if w.Code != status {
+		t.Fatalf("%s %s: got %d, want %d: %s", method, path, w.Code, status, w.Body.String())
+	}
+
+	if contentType := w.Header().Get(utility.ContentType); contentType != utility.ApplicationProblem {
+		t.Fatalf("%s %s: content type %q", method, path, contentType)
+	}
+
+	var p utility.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("%s %s: decode problem: %v", method, path, err)
+	}
+
+	if p.Code != code {
+		t.Fatalf("%s %s: code %q, want %q", method, path, p.Code, code)
+	}
+
+	return p
+}
+
+func (c *client) createList(t *testing.T, title string) int {
+	t.Helper()
+
+	var created struct{ Id int }
+	c.mustDo(t, http.MethodPost, "/api/lists/", todo.TodoList{Title: title}, http.StatusOK, &created)
+
+	return created.Id
+}
+
+func (c *client) createItem(t *testing.T, listId int, title string) int {
+	t.Helper()
+
+	var created struct{ Id int }
+	c.mustDo(t, http.MethodPost, fmt.Sprintf("/api/lists/%d/items/", listId), todo.TodoItem{Title: title}, http.StatusOK, &created)
+
+	return created.Id
+}
+
+type listsPage struct {
+	Data       []todo.TodoList `json:"data"`
+	NextCursor string          `json:"next_cursor"`
+	Total      int             `json:"total"`
+}
+
+type itemsPage struct {
+	Data       []todo.TodoItem `json:"data"`
+	NextCursor string          `json:"next_cursor"`
+	Total      int             `json:"total"`
+}
+
+func TestAuth(t *testing.T) {
+	e := newEnv(t)
+	anonymous := e.anonymous()
+
+	t.Run("sign up", func(t *testing.T) {
+		e.signUp(t, "alice")
+
+		anonymous.problem(t, http.MethodPost, "/auth/sign-up/",
+			todo.SignUpInput{Name: "Alice", Username: "alice", Password: password}, http.StatusConflict, "username_taken")
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		p := anonymous.problem(t, http.MethodPost, "/auth/sign-up/",
+			todo.SignUpInput{Username: "b", Password: "short"}, http.StatusBadRequest, "invalid_input")
+
+		fields := map[string]bool{}
+		for _, v := range p.Errors {
+			fields[v.Field] = true
+		}
+		for _, field := range []string{"name", "username", "password"} {
+			if !fields[field] {
+				t.Errorf("no violation for %s in %+v", field, p.Errors)
+			}
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		anonymous.problem(t, http.MethodPost, "/auth/sign-in/",
+			map[string]string{"username": "alice", "password": "Wrong1234"}, http.StatusUnauthorized, "invalid_credentials")
+		anonymous.problem(t, http.MethodPost, "/auth/sign-in/",
+			map[string]string{"username": "nobody", "password": password}, http.StatusUnauthorized, "invalid_credentials")
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		anonymous.problem(t, http.MethodGet, "/api/lists/", nil, http.StatusUnauthorized, "unauthorized")
+
+		forged := &client{env: e, tokens: todo.Tokens{AccessToken: "not.a.token"}}
+		forged.problem(t, http.MethodGet, "/api/lists/", nil, http.StatusUnauthorized, "invalid_token")
+	})
+
+	t.Run("refresh rotates the token", func(t *testing.T) {
+		c := e.signUp(t, "bob")
+		old := c.tokens
+
+		var refreshed todo.Tokens
+		anonymous.mustDo(t, http.MethodPost, "/auth/refresh/", map[string]string{"refresh_token": old.RefreshToken}, http.StatusOK, &refreshed)
+		if refreshed.RefreshToken == old.RefreshToken {
+			t.Fatal("refresh token was not rotated")
+		}
+
+		anonymous.problem(t, http.MethodPost, "/auth/refresh/", map[string]string{"refresh_token": old.RefreshToken},
+			http.StatusUnauthorized, "invalid_refresh_token")
+
+		c.tokens = refreshed
+		c.mustDo(t, http.MethodGet, "/api/lists/", nil, http.StatusOK, nil)
+	})
+
+	t.Run("logout revokes the session", func(t *testing.T) {
+		c := e.signUp(t, "carol")
+		c.mustDo(t, http.MethodGet, "/api/lists/", nil, http.StatusOK, nil)
+
+		anonymous.mustDo(t, http.MethodPost, "/auth/logout/", map[string]string{"refresh_token": c.tokens.RefreshToken}, http.StatusOK, nil)
+
+		c.problem(t, http.MethodGet, "/api/lists/", nil, http.StatusUnauthorized, "session_revoked")
+		anonymous.problem(t, http.MethodPost, "/auth/refresh/", map[string]string{"refresh_token": c.tokens.RefreshToken},
+			http.StatusUnauthorized, "session_revoked")
+	})
+
+	t.Run("jwks", func(t *testing.T) {
+		anonymous.mustDo(t, http.MethodGet, "/.well-known/jwks.json", nil, http.StatusOK, nil)
+	})
+}
+
+func TestLists(t *testing.T) {
+	e := newEnv(t)
+	alice := e.signUp(t, "alice")
+
+	id := alice.createList(t, "Groceries")
+	alice.createList(t, "Chores")
+
+	var list todo.TodoList
+	alice.mustDo(t, http.MethodGet, fmt.Sprintf("/api/lists/%d", id), nil, http.StatusOK, &list)
+	if list.Title != "Groceries" {
+		t.Fatalf("title %q", list.Title)
+	}
+
+	if !e.redis.Exists(cache.Lists.Key(id)) {
+		t.Error("list was not cached")
+	}
+
+	title := "Weekly groceries"
+	alice.mustDo(t, http.MethodPut, fmt.Sprintf("/api/lists/%d", id), todo.UpdateListInput{Title: &title}, http.StatusOK, nil)
+
+	if e.redis.Exists(cache.Lists.Key(id)) {
+		t.Error("updated list is still cached")
+	}
+
+	alice.mustDo(t, http.MethodGet, fmt.Sprintf("/api/lists/%d", id), nil, http.StatusOK, &list)
+	if list.Title != title {
+		t.Fatalf("title %q after update", list.Title)
+	}
+
+	var page listsPage
+	alice.mustDo(t, http.MethodGet, "/api/lists/?limit=1", nil, http.StatusOK, &page)
+	if page.Total != 2 || len(page.Data) != 1 || page.NextCursor == "" {
+		t.Fatalf("first page %+v", page)
+	}
+
+	alice.mustDo(t, http.MethodGet, "/api/lists/?limit=1&cursor="+page.NextCursor, nil, http.StatusOK, &page)
+	if len(page.Data) != 1 || page.Data[0].Title != "Chores" || page.NextCursor != "" {
+		t.Fatalf("second page %+v", page)
+	}
+
+	alice.problem(t, http.MethodGet, "/api/lists/?limit=0", nil, http.StatusBadRequest, "invalid_input")
+	alice.problem(t, http.MethodGet, "/api/lists/?cursor=garbage", nil, http.StatusBadRequest, "invalid_cursor")
+	alice.problem(t, http.MethodPost, "/api/lists/", todo.TodoList{}, http.StatusBadRequest, "invalid_input")
+	alice.problem(t, http.MethodPut, fmt.Sprintf("/api/lists/%d", id), todo.UpdateListInput{}, http.StatusBadRequest, "invalid_input")
+
+	// decoder and strconv messages name Go types, so they are not shown to clients
+	if p := alice.problem(t, http.MethodPost, "/api/lists/", "not an object", http.StatusBadRequest, "invalid_input"); p.Detail != "request body must be a valid JSON object" {
+		t.Fatalf("detail %q", p.Detail)
+	}
+	if p := alice.problem(t, http.MethodGet, "/api/lists/first", nil, http.StatusBadRequest, "invalid_input"); p.Detail != "id must be a number" {
+		t.Fatalf("detail %q", p.Detail)
+	}
+
+	alice.mustDo(t, http.MethodDelete, fmt.Sprintf("/api/lists/%d", id), nil, http.StatusOK, nil)
+	alice.problem(t, http.MethodGet, fmt.Sprintf("/api/lists/%d", id), nil, http.StatusNotFound, "list_not_found")
+
+	alice.mustDo(t, http.MethodGet, "/api/lists/", nil, http.StatusOK, &page)
+	if page.Total != 1 {
+		t.Fatalf("%d lists after delete", page.Total)
+	}
+}
+
+func TestItems(t *testing.T) {
+	e := newEnv(t)
+	alice := e.signUp(t, "alice")
+
+	listId := alice.createList(t, "Groceries")
+	id := alice.createItem(t, listId, "Milk")
+	alice.createItem(t, listId, "Bread")
+
+	var item todo.TodoItem
+	alice.mustDo(t, http.MethodGet, fmt.Sprintf("/api/items/%d", id), nil, http.StatusOK, &item)
+	if item.Title != "Milk" || item.Done {
+		t.Fatalf("item %+v", item)
+	}
+
+	done := true
+	alice.mustDo(t, http.MethodPut, fmt.Sprintf("/api/items/%d", id), todo.UpdateItemInput{Done: &done}, http.StatusOK, nil)
+
+	alice.mustDo(t, http.MethodGet, fmt.Sprintf("/api/items/%d", id), nil, http.StatusOK, &item)
+	if !item.Done || item.CompletedAt == nil {
+		t.Fatalf("item %+v after completing it", item)
+	}
+
+	var page itemsPage
+	alice.mustDo(t, http.MethodGet, fmt.Sprintf("/api/lists/%d/items/?done=false", listId), nil, http.StatusOK, &page)
+	if page.Total != 1 || page.Data[0].Title != "Bread" {
+		t.Fatalf("not done items %+v", page)
+	}
+
+	priority := 7
+	alice.problem(t, http.MethodPut, fmt.Sprintf("/api/items/%d", id), todo.UpdateItemInput{Priority: &priority}, http.StatusBadRequest, "invalid_input")
+
+	alice.mustDo(t, http.MethodDelete, fmt.Sprintf("/api/items/%d", id), nil, http.StatusOK, nil)
+	alice.problem(t, http.MethodGet, fmt.Sprintf("/api/items/%d", id), nil, http.StatusNotFound, "item_not_found")
+
+	alice.mustDo(t, http.MethodGet, fmt.Sprintf("/api/lists/%d/items/", listId), nil, http.StatusOK, &page)
+	if page.Total != 1 {
+		t.Fatalf("%d items after delete", page.Total)
+	}
+
+	alice.mustDo(t, http.MethodDelete, fmt.Sprintf("/api/lists/%d", listId), nil, http.StatusOK, nil)
+	alice.problem(t, http.MethodGet, fmt.Sprintf("/api/items/%d", page.Data[0].Id), nil, http.StatusNotFound, "item_not_found")
+}
+
+func TestOwnership(t *testing.T) {
+	e := newEnv(t)
+	alice, bob := e.signUp(t, "alice"), e.signUp(t, "bob")
+
+	listId := alice.createList(t, "Groceries")
+	itemId := alice.createItem(t, listId, "Milk")
+	listPath, itemPath := fmt.Sprintf("/api/lists/%d", listId), fmt.Sprintf("/api/items/%d", itemId)
+	title := "Mine now"
+
+	t.Run("strangers see nothing", func(t *testing.T) {
+		// the list is warm in the cache, which must not leak it either
+		alice.mustDo(t, http.MethodGet, listPath, nil, http.StatusOK, nil)
+		alice.mustDo(t, http.MethodGet, itemPath, nil, http.StatusOK, nil)
+
+		bob.problem(t, http.MethodGet, listPath, nil, http.StatusNotFound, "list_not_found")
+		bob.problem(t, http.MethodPut, listPath, todo.UpdateListInput{Title: &title}, http.StatusNotFound, "list_not_found")
+		bob.problem(t, http.MethodDelete, listPath, nil, http.StatusNotFound, "list_not_found")
+		bob.problem(t, http.MethodGet, listPath+"/items/", nil, http.StatusNotFound, "list_not_found")
+		bob.problem(t, http.MethodPost, listPath+"/items/", todo.TodoItem{Title: "Beer"}, http.StatusNotFound, "list_not_found")
+		bob.problem(t, http.MethodGet, itemPath, nil, http.StatusNotFound, "item_not_found")
+		bob.problem(t, http.MethodPut, itemPath, todo.UpdateItemInput{Title: &title}, http.StatusNotFound, "item_not_found")
+		bob.problem(t, http.MethodDelete, itemPath, nil, http.StatusNotFound, "item_not_found")
+		bob.problem(t, http.MethodGet, listPath+"/collaborators", nil, http.StatusNotFound, "list_not_found")
+
+		var page listsPage
+		bob.mustDo(t, http.MethodGet, "/api/lists/", nil, http.StatusOK, &page)
+		if page.Total != 0 {
+			t.Fatalf("stranger sees %d lists", page.Total)
+		}
+	})
+
+	t.Run("viewers can only read", func(t *testing.T) {
+		alice.mustDo(t, http.MethodPost, listPath+"/share", todo.ShareListInput{Username: "bob", Role: todo.RoleViewer}, http.StatusOK, nil)
+
+		bob.mustDo(t, http.MethodGet, listPath, nil, http.StatusOK, nil)
+		bob.mustDo(t, http.MethodGet, itemPath, nil, http.StatusOK, nil)
+		bob.problem(t, http.MethodPut, listPath, todo.UpdateListInput{Title: &title}, http.StatusForbidden, "forbidden")
+		bob.problem(t, http.MethodPut, itemPath, todo.UpdateItemInput{Title: &title}, http.StatusForbidden, "forbidden")
+		bob.problem(t, http.MethodPost, listPath+"/items/", todo.TodoItem{Title: "Beer"}, http.StatusForbidden, "forbidden")
+		bob.problem(t, http.MethodPost, listPath+"/share", todo.ShareListInput{Username: "carol", Role: todo.RoleViewer}, http.StatusForbidden, "forbidden")
+
+		var page listsPage
+		bob.mustDo(t, http.MethodGet, "/api/lists/", nil, http.StatusOK, &page)
+		if page.Total != 1 {
+			t.Fatalf("viewer sees %d lists", page.Total)
+		}
+	})
+
+	t.Run("editors can change items", func(t *testing.T) {
+		alice.mustDo(t, http.MethodPost, listPath+"/share", todo.ShareListInput{Username: "bob", Role: todo.RoleEditor}, http.StatusOK, nil)
+
+		bob.mustDo(t, http.MethodPut, itemPath, todo.UpdateItemInput{Title: &title}, http.StatusOK, nil)
+		bob.createItem(t, listId, "Beer")
+		bob.problem(t, http.MethodDelete, listPath, nil, http.StatusForbidden, "forbidden")
+		bob.problem(t, http.MethodDelete, fmt.Sprintf("%s/collaborators/%d", listPath, ownerId(t, alice, listPath)), nil, http.StatusForbidden, "forbidden")
+
+		var item todo.TodoItem
+		alice.mustDo(t, http.MethodGet, itemPath, nil, http.StatusOK, &item)
+		if item.Title != title {
+			t.Fatalf("owner sees title %q", item.Title)
+		}
+	})
+
+	t.Run("the last owner stays", func(t *testing.T) {
+		var collaborators struct{ Data []todo.Collaborator }
+		alice.mustDo(t, http.MethodGet, listPath+"/collaborators", nil, http.StatusOK, &collaborators)
+		if len(collaborators.Data) != 2 {
+			t.Fatalf("collaborators %+v", collaborators.Data)
+		}
+
+		alice.problem(t, http.MethodDelete, fmt.Sprintf("%s/collaborators/%d", listPath, ownerId(t, alice, listPath)), nil, http.StatusConflict, "last_owner")
+		alice.problem(t, http.MethodPost, listPath+"/share", todo.ShareListInput{Username: "alice", Role: todo.RoleViewer}, http.StatusConflict, "last_owner")
+	})
+
+	t.Run("revoked access", func(t *testing.T) {
+		var collaborators struct{ Data []todo.Collaborator }
+		alice.mustDo(t, http.MethodGet, listPath+"/collaborators", nil, http.StatusOK, &collaborators)
+
+		for _, c := range collaborators.Data {
+			if c.Username == "bob" {
+				alice.mustDo(t, http.MethodDelete, fmt.Sprintf("%s/collaborators/%d", listPath, c.UserId), nil, http.StatusOK, nil)
+			}
+		}
+
+		bob.problem(t, http.MethodGet, listPath, nil, http.StatusNotFound, "list_not_found")
+		bob.problem(t, http.MethodGet, itemPath, nil, http.StatusNotFound, "item_not_found")
+
+		var page listsPage
+		bob.mustDo(t, http.MethodGet, "/api/lists/", nil, http.StatusOK, &page)
+		if page.Total != 0 {
+			t.Fatalf("revoked user sees %d lists", page.Total)
+		}
+	})
+
+}
+
+func ownerId(t *testing.T, c *client, listPath string) int {
+	t.Helper()
+
+	var collaborators struct{ Data []todo.Collaborator }
+	c.mustDo(t, http.MethodGet, listPath+"/collaborators", nil, http.StatusOK, &collaborators)
+
+	for _, collaborator := range collaborators.Data {
+		if collaborator.Role == todo.RoleOwner {
+			return collaborator.UserId
+		}
+	}
+
+	t.Fatalf("no owner among %+v", collaborators.Data)
+	return 0
+}
+
+func TestSearch(t *testing.T) {
+	e := newEnv(t)
+	alice, bob := e.signUp(t, "alice"), e.signUp(t, "bob")
+
+	listId := alice.createList(t, "Groceries")
+	alice.createItem(t, listId, "Oat milk")
+
+	var hits struct{ Data []todo.SearchHit }
+	alice.mustDo(t, http.MethodGet, "/api/search?q=milk", nil, http.StatusOK, &hits)
+	if len(hits.Data) != 1 || hits.Data[0].Kind != todo.SearchKindItem {
+		t.Fatalf("hits %+v", hits.Data)
+	}
+
+	bob.mustDo(t, http.MethodGet, "/api/search?q=milk", nil, http.StatusOK, &hits)
+	if len(hits.Data) != 0 {
+		t.Fatalf("stranger finds %+v", hits.Data)
+	}
+
+	alice.problem(t, http.MethodGet, "/api/search?q=", nil, http.StatusBadRequest, "invalid_search")
+}
+
+func TestErrorsAreLogged(t *testing.T) {
+	e := newEnv(t)
+	alice := e.signUp(t, "alice")
+
+	alice.problem(t, http.MethodGet, "/api/lists/12345", nil, http.StatusNotFound, "list_not_found")
+
+	rejected := e.logs.FilterMessage("request rejected").FilterField(zap.String("code", "list_not_found"))
+	if rejected.Len() != 1 {
+		t.Fatalf("%d rejections logged, all entries: %+v", rejected.Len(), e.logs.All())
+	}
+}
+
+func TestHealth(t *testing.T) {
+	e := newEnv(t)
+
+	var status utility.StatusResponse
+	e.anonymous().mustDo(t, http.MethodGet, "/health/live", nil, http.StatusOK, &status)
+	e.anonymous().mustDo(t, http.MethodGet, "/health/ready", nil, http.StatusOK, &status)
+	if status.Status != "ok" {
+		t.Fatalf("status %q", status.Status)
+	}
+}
+
+func TestReadinessHidesCause(t *testing.T) {
+	srv := api.NewServer(config.NewStore(testenv.Config(), config.Options{}), middlewares.NewUserAuthMiddleware(nil))
+	srv.HandleHealth(func(ctx context.Context) error {
+		return errors.New("postgres: dial tcp 10.0.0.5:5432: connection refused")
+	})
+
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var p utility.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil || w.Code != http.StatusServiceUnavailable || p.Detail != "not ready" {
+		t.Fatalf("got %d %s: %v", w.Code, w.Body.String(), err)
+	}
+}
+
+func TestMetricsRequireToken(t *testing.T) {
+	cfg := testenv.Config()
+	srv := api.NewServer(config.NewStore(cfg, config.Options{}), middlewares.NewUserAuthMiddleware(nil))
+
+	scrape := func(header string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	for _, header := range []string{"", "Bearer wrong", "Bearer " + cfg.Metrics.Token + "x"} {
+		if w := scrape(header); w.Code != http.StatusUnauthorized {
+			t.Fatalf("%q: status %d", header, w.Code)
+		}
+	}
+
+	w := scrape("Bearer " + cfg.Metrics.Token)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "go_goroutines") {
+		t.Fatalf("got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminLogLevel(t *testing.T) {
+	e := newEnv(t)
+	alice := e.signUp(t, "alice")
+
+	alice.problem(t, http.MethodGet, "/admin/log/level", nil, http.StatusForbidden, "admin_required")
+	e.anonymous().problem(t, http.MethodGet, "/admin/log/level", nil, http.StatusUnauthorized, "unauthorized")
+
+	if err := e.services.AuthService.SetAdmin(context.Background(), "alice", true); err != nil {
+		t.Fatalf("grant admin: %v", err)
+	}
+
+	var level struct{ Level string }
+	alice.mustDo(t, http.MethodGet, "/admin/log/level", nil, http.StatusOK, &level)
+	if level.Level != "debug" {
+		t.Fatalf("level %q", level.Level)
+	}
+
+	alice.mustDo(t, http.MethodPut, "/admin/log/level", map[string]string{"level": "warn"}, http.StatusOK, &level)
+	if level.Level != "warn" || e.level.Level() != zap.WarnLevel {
+		t.Fatalf("level %q, logger at %s", level.Level, e.level.Level())
+	}
+
+	alice.problem(t, http.MethodPut, "/admin/log/level", map[string]string{"level": "verbose"}, http.StatusBadRequest, "bad_request")
+}
+
+func TestDebugVarsRequireAdmin(t *testing.T) {
+	e := newEnv(t)
+	alice := e.signUp(t, "alice")
+
+	e.anonymous().problem(t, http.MethodGet, "/admin/debug/vars", nil, http.StatusUnauthorized, "unauthorized")
+	alice.problem(t, http.MethodGet, "/admin/debug/vars", nil, http.StatusForbidden, "admin_required")
+
+	if err := e.services.AuthService.SetAdmin(context.Background(), "alice", true); err != nil {
+		t.Fatalf("grant admin: %v", err)
+	}
+
+	var vars map[string]interface{}
+	alice.mustDo(t, http.MethodGet, "/admin/debug/vars", nil, http.StatusOK, &vars)
+	if _, ok := vars["memstats"]; !ok {
+		t.Fatalf("vars %v", vars)
+	}
+}
+
+// logRepository stands in for the MongoDB log collection with a single entry.
+type logRepository struct{}
+
+func (logRepository) EnsureIndexes(ctx context.Context, ttl time.Duration) error {
+	return nil
+}
+
+func (logRepository) Query(ctx context.Context, filter todo.LogFilter) (todo.LogsPage, error) {
+	return todo.LogsPage{Data: []todo.LogEntry{{Level: "info", Message: "request served"}}}, nil
+}
+
+func (logRepository) Size(ctx context.Context) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+func (logRepository) DeleteOldest(ctx context.Context, n int64) (int64, error) {
+	return 0, nil
+}
+
+func TestAdminLogs(t *testing.T) {
+	e := newEnv(t)
+	alice := e.signUp(t, "alice")
+
+	e.anonymous().problem(t, http.MethodGet, "/admin/logs", nil, http.StatusUnauthorized, "unauthorized")
+	alice.problem(t, http.MethodGet, "/admin/logs", nil, http.StatusForbidden, "admin_required")
+
+	if err := e.services.AuthService.SetAdmin(context.Background(), "alice", true); err != nil {
+		t.Fatalf("grant admin: %v", err)
+	}
+
+	var page struct{ Data []todo.LogEntry }
+	alice.mustDo(t, http.MethodGet, "/admin/logs?level=info,warn&limit=10", nil, http.StatusOK, &page)
+	if len(page.Data) != 1 || page.Data[0].Message != "request served" {
+		t.Fatalf("page %+v", page)
+	}
+
+	for query, detail := range map[string]string{
+		"level=verbose":  `unknown level "verbose"`,
+		"limit=0":        "limit must be between 1 and 200",
+		"limit=ten":      "limit must be a number",
+		"user_id=alice":  "user_id must be a number",
+		"from=yesterday": "from must be an RFC 3339 time such as 2024-05-01T10:00:00Z",
+		"to=x&from=y":    "from must be an RFC 3339 time such as 2024-05-01T10:00:00Z",
+		"from=2024-05-01T10:00:00Z&to=2024-05-01T10:00:00Z": "from must be before to",
+	} {
+		if p := alice.problem(t, http.MethodGet, "/admin/logs?"+query, nil, http.StatusBadRequest, "invalid_input"); p.Detail != detail {
+			t.Errorf("%s: detail %q, want %q", query, p.Detail, detail)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/logs"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type logLevel struct {
+	Level string `json:"level" example:"debug"`
+}
+
+// GetLogLevel godoc
+// @Summary Get log level
+// @Security ApiKeyAuth
+// @Tags admin
+// @Description minimum level of the entries written to every log sink
+// @ID get-log-level
+// @Produce  json
+// @Success 200 {object} logLevel
+// @Failure 401,403 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /admin/log/level [get]
+func GetLogLevel(level zap.AtomicLevel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeLogLevel(w, r, level)
+	}
+}
+
+// SetLogLevel godoc
+// @Summary Set log level
+// @Security ApiKeyAuth
+// @Tags admin
+// @Description change the log level until the next restart or config reload
+// @ID set-log-level
+// @Accept  json
+// @Produce  json
+// @Param input body logLevel true "debug, info, warn or error"
+// @Success 200 {object} logLevel
+// @Failure 400,401,403 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /admin/log/level [put]
+func SetLogLevel(level zap.AtomicLevel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input logLevel
+		if err := decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		parsed, err := zapcore.ParseLevel(input.Level)
+		if err != nil || parsed < zapcore.DebugLevel || parsed > zapcore.ErrorLevel {
+			utility.NewErrorResponse(w, r, http.StatusBadRequest, "level must be one of debug, info, warn or error")
+			return
+		}
+
+		previous := level.Level()
+		level.SetLevel(parsed)
+		logging.FromContext(r.Context()).Info("log level changed", zap.Stringer("from", previous), zap.Stringer("to", parsed))
+
+		writeLogLevel(w, r, level)
+	}
+}
+
+func writeLogLevel(w http.ResponseWriter, r *http.Request, level zap.AtomicLevel) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	encodeJSON(w, r, logLevel{Level: level.String()})
+}
+
+type getLogsResponse struct {
+	Data       []todo.LogEntry `json:"data"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// GetLogs godoc
+// @Summary Query logs
+// @Security ApiKeyAuth
+// @Tags admin
+// @Description application log entries, newest first
+// @ID get-logs
+// @Produce  json
+// @Param level query string false "comma-separated levels, e.g. warn,error"
+// @Param from query string false "RFC 3339 time, inclusive"
+// @Param to query string false "RFC 3339 time, exclusive"
+// @Param request_id query string false "request id"
+// @Param user_id query int false "user id"
+// @Param caller query string false "beginning of the caller, e.g. handler/"
+// @Param limit query int false "page size, 1 to 200" default(50)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} getLogsResponse
+// @Failure 400,401,403 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /admin/logs [get]
+func GetLogs(service logs.LogService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseLogFilter(r)
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		page, err := service.Query(r.Context(), filter)
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		encodeJSON(w, r, getLogsResponse{Data: page.Data, NextCursor: page.NextCursor})
+	}
+}
+
+// parseLogFilter reads the level, from, to, request_id, user_id, caller, limit and cursor
+// query parameters.
+func parseLogFilter(r *http.Request) (todo.LogFilter, error) {
+	query := r.URL.Query()
+
+	filter := todo.LogFilter{
+		RequestId: query.Get("request_id"),
+		Caller:    query.Get("caller"),
+		Limit:     todo.DefaultPageLimit,
+		Cursor:    query.Get("cursor"),
+	}
+
+	if levels := query.Get("level"); levels != "" {
+		filter.Levels = strings.Split(strings.ToLower(levels), ",")
+	}
+
+	// a slice rather than a map, so the same parameter is reported when both are invalid
+	for _, bound := range []struct {
+		name string
+		t    *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := query.Get(bound.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, todo.ErrInvalidInput.WithMessage(bound.name + " must be an RFC 3339 time such as 2024-05-01T10:00:00Z")
+			}
+			*bound.t = parsed
+		}
+	}
+
+	if userId := query.Get("user_id"); userId != "" {
+		n, err := strconv.Atoi(userId)
+		if err != nil {
+			return filter, todo.ErrInvalidInput.WithMessage("user_id must be a number")
+		}
+		filter.UserId = n
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return filter, todo.ErrInvalidInput.WithMessage("limit must be a number")
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"encoding/json"
 	todo "github.com/dafuqqqyunglean/todoRestAPI"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/auth"
@@ -15,24 +14,24 @@ import (
 // @ID create-account
 // @Accept  json
 // @Produce  json
-// @Param input body todo.User true "account info"
+// @Param input body todo.SignUpInput true "account info"
 // @Success 200 {integer} integer 1
-// @Failure 400,404 {object} utility.ErrorResponse
-// @Failure 500 {object} utility.ErrorResponse
-// @Failure default {object} utility.ErrorResponse
+// @Failure 400,409 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
 // @Router /auth/sign-up [post]
 func SignUp(service auth.AuthorizationService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var input todo.User
+		var input todo.SignUpInput
 
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+		if err := decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		id, err := service.CreateUser(input)
+		id, err := service.CreateUser(r.Context(), input)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
@@ -42,10 +41,7 @@ func SignUp(service auth.AuthorizationService) http.HandlerFunc {
 		response := map[string]interface{}{
 			"id": id,
 		}
-		if err = json.NewEncoder(w).Encode(response); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		encodeJSON(w, r, response)
 	}
 }
 
@@ -62,35 +58,120 @@ type signInInput struct {
 // @Accept  json
 // @Produce  json
 // @Param input body signInInput true "credentials"
-// @Success 200 {string} string "token"
-// @Failure 400,404 {object} utility.ErrorResponse
-// @Failure 500 {object} utility.ErrorResponse
-// @Failure default {object} utility.ErrorResponse
+// @Success 200 {object} todo.Tokens
+// @Failure 400,401 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
 // @Router /auth/sign-in [post]
 func SignIn(service auth.AuthorizationService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var input signInInput
 
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+		if err := decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		token, err := service.GenerateToken(input.Username, input.Password)
+		tokens, err := service.GenerateToken(r.Context(), input.Username, input.Password)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		response := map[string]interface{}{
-			"token": token,
+		encodeJSON(w, r, tokens)
+	}
+}
+
+type refreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh godoc
+// @Summary Refresh
+// @Tags auth
+// @Description exchange a refresh token for a new token pair
+// @ID refresh
+// @Accept  json
+// @Produce  json
+// @Param input body refreshTokenInput true "refresh token"
+// @Success 200 {object} todo.Tokens
+// @Failure 400 {object} utility.Problem
+// @Failure 401 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /auth/refresh [post]
+func Refresh(service auth.AuthorizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input refreshTokenInput
+
+		if err := decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		tokens, err := service.RefreshToken(r.Context(), input.RefreshToken)
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		encodeJSON(w, r, tokens)
+	}
+}
+
+// Logout godoc
+// @Summary Logout
+// @Tags auth
+// @Description revoke the session of a refresh token
+// @ID logout
+// @Accept  json
+// @Produce  json
+// @Param input body refreshTokenInput true "refresh token"
+// @Success 200 {object} utility.StatusResponse
+// @Failure 400 {object} utility.Problem
+// @Failure 401 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /auth/logout [post]
+func Logout(service auth.AuthorizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input refreshTokenInput
+
+		if err := decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
 		}
-		if err = json.NewEncoder(w).Encode(response); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+
+		if err := service.Logout(r.Context(), input.RefreshToken); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		encodeJSON(w, r, utility.StatusResponse{Status: "ok"})
+	}
+}
+
+// JWKS godoc
+// @Summary JWKS
+// @Tags auth
+// @Description public keys for verifying access tokens signed with asymmetric algorithms
+// @ID jwks
+// @Produce  json
+// @Success 200 {object} auth.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func JWKS(service auth.AuthorizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(http.StatusOK)
+
+		encodeJSON(w, r, service.JWKS())
 	}
 }
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ReadinessFunc returns nil while the application can serve requests.
+type ReadinessFunc func(ctx context.Context) error
+
+// Live godoc
+// @Summary Liveness
+// @Tags health
+// @Description answers as long as the process serves HTTP
+// @ID live
+// @Produce  json
+// @Success 200 {object} utility.StatusResponse
+// @Router /health/live [get]
+func Live() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, r)
+	}
+}
+
+// Ready godoc
+// @Summary Readiness
+// @Tags health
+// @Description answers 200 once every backend is connected and until shutdown begins
+// @ID ready
+// @Produce  json
+// @Success 200 {object} utility.StatusResponse
+// @Failure 503 {object} utility.Problem
+// @Router /health/ready [get]
+func Ready(ready ReadinessFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the probe is public, so the cause, which names hosts and ports, is only logged
+		if err := ready(r.Context()); err != nil {
+			logging.FromContext(r.Context()).Warn("not ready", zap.Error(err))
+			utility.NewErrorResponse(w, r, http.StatusServiceUnavailable, "not ready")
+			return
+		}
+
+		writeStatus(w, r)
+	}
+}
+
+func writeStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	encodeJSON(w, r, utility.StatusResponse{Status: "ok"})
+}
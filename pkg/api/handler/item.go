@@ -1,35 +1,31 @@
 package handler
 
 import (
-	"context"
-	"encoding/json"
 	todo "github.com/dafuqqqyunglean/todoRestAPI"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/item"
-	"github.com/gorilla/mux"
 	"net/http"
-	"strconv"
 )
 
-func CreateItem(ctx context.Context, service item.TodoItemService) http.HandlerFunc {
+func CreateItem(service item.TodoItemService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		listId, err := strconv.Atoi(mux.Vars(r)["id"])
+		listId, err := pathInt(r, "id")
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		var input todo.TodoItem
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+		if err := decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		id, err := service.Create(ctx, userId, listId, input)
+		id, err := service.Create(r.Context(), userId, listId, input)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
@@ -39,118 +35,147 @@ func CreateItem(ctx context.Context, service item.TodoItemService) http.HandlerF
 		response := map[string]interface{}{
 			"id": id,
 		}
-		if err = json.NewEncoder(w).Encode(response); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		encodeJSON(w, r, response)
 	}
 }
 
-func GetAllItems(ctx context.Context, service item.TodoItemService) http.HandlerFunc {
+type getAllItemsResponse struct {
+	Data       []todo.TodoItem `json:"data"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Total      int             `json:"total"`
+}
+
+// GetAllItems godoc
+// @Summary Get All Items
+// @Security ApiKeyAuth
+// @Tags items
+// @Description get a page of items of the list
+// @ID get-all-items
+// @Produce  json
+// @Param id path int true "list id"
+// @Param limit query int false "page size" default(50) maximum(200)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field" Enums(id, title, created_at) default(id)
+// @Param order query string false "sort order" Enums(asc, desc) default(asc)
+// @Param done query bool false "only done or only not done items"
+// @Param q query string false "text the title or description contains"
+// @Param overdue query bool false "only not done items past their due date"
+// @Param due_within query string false "only not done items due within the duration, e.g. 24h"
+// @Success 200 {object} getAllItemsResponse
+// @Failure 400,404 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /api/lists/{id}/items [get]
+func GetAllItems(service item.TodoItemService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		listId, err := strconv.Atoi(mux.Vars(r)["id"])
+		listId, err := pathInt(r, "id")
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		params, err := parsePageParams(r)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		items, err := service.GetAll(ctx, userId, listId)
+		filter, err := parseItemFilter(r)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		page, err := service.GetAll(r.Context(), userId, listId, params, filter)
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		if err = json.NewEncoder(w).Encode(items); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
+		response := getAllItemsResponse{
+			Data:       page.Data,
+			NextCursor: page.NextCursor,
+			Total:      page.Total,
 		}
+		encodeJSON(w, r, response)
 	}
 }
 
-func GetItemById(ctx context.Context, service item.TodoItemService) http.HandlerFunc {
+func GetItemById(service item.TodoItemService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		itemId, err := strconv.Atoi(mux.Vars(r)["id"])
+		itemId, err := pathInt(r, "id")
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		item, err := service.GetById(ctx, userId, itemId)
+		item, err := service.GetById(r.Context(), userId, itemId)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		if err = json.NewEncoder(w).Encode(item); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		encodeJSON(w, r, item)
 	}
 }
 
-func DeleteItem(ctx context.Context, service item.TodoItemService) http.HandlerFunc {
+func DeleteItem(service item.TodoItemService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		itemId, err := strconv.Atoi(mux.Vars(r)["id"])
+		itemId, err := pathInt(r, "id")
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		err = service.Delete(ctx, userId, itemId)
+		err = service.Delete(r.Context(), userId, itemId)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		if err = json.NewEncoder(w).Encode(utility.StatusResponse{Status: "ok"}); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		encodeJSON(w, r, utility.StatusResponse{Status: "ok"})
 	}
 }
 
-func UpdateItem(ctx context.Context, service item.TodoItemService) http.HandlerFunc {
+func UpdateItem(service item.TodoItemService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		itemId, err := strconv.Atoi(mux.Vars(r)["id"])
+		itemId, err := pathInt(r, "id")
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		var input todo.UpdateItemInput
-		if err = json.NewDecoder(r.Body).Decode(&input); err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+		if err = decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		if err = service.Update(ctx, userId, itemId, input); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+		if err = service.Update(r.Context(), userId, itemId, input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		if err = json.NewEncoder(w).Encode(utility.StatusResponse{Status: "ok"}); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		encodeJSON(w, r, utility.StatusResponse{Status: "ok"})
 	}
 }
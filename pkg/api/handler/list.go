@@ -1,14 +1,10 @@
 package handler
 
 import (
-	"context"
-	"encoding/json"
 	todo "github.com/dafuqqqyunglean/todoRestAPI"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/list"
-	"github.com/gorilla/mux"
 	"net/http"
-	"strconv"
 )
 
 // CreateList godoc
@@ -21,23 +17,23 @@ import (
 // @Produce  json
 // @Param input body todo.TodoList true "list info"
 // @Success 200 {integer} integer 1
-// @Failure 400,404 {object} utility.ErrorResponse
-// @Failure 500 {object} utility.ErrorResponse
-// @Failure default {object} utility.ErrorResponse
+// @Failure 400,404 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
 // @Router /api/lists [post]
-func CreateList(ctx context.Context, service list.TodoListService) http.HandlerFunc {
+func CreateList(service list.TodoListService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
 		var input todo.TodoList
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+		if err := decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		id, err := service.Create(ctx, userId, input)
+		id, err := service.Create(r.Context(), userId, input)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
@@ -47,37 +43,46 @@ func CreateList(ctx context.Context, service list.TodoListService) http.HandlerF
 		response := map[string]interface{}{
 			"id": id,
 		}
-		if err = json.NewEncoder(w).Encode(response); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		encodeJSON(w, r, response)
 	}
 }
 
 type getAllListsResponse struct {
-	Data []todo.TodoList `json:"data"`
+	Data       []todo.TodoList `json:"data"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Total      int             `json:"total"`
 }
 
 // GetAllLists godoc
 // @Summary Get All Lists
 // @Security ApiKeyAuth
 // @Tags lists
-// @Description get all lists
+// @Description get a page of lists
 // @ID get-all-lists
 // @Accept  json
 // @Produce  json
+// @Param limit query int false "page size" default(50) maximum(200)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field" Enums(id, title, created_at) default(id)
+// @Param order query string false "sort order" Enums(asc, desc) default(asc)
 // @Success 200 {object} getAllListsResponse
-// @Failure 400,404 {object} utility.ErrorResponse
-// @Failure 500 {object} utility.ErrorResponse
-// @Failure default {object} utility.ErrorResponse
+// @Failure 400,404 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
 // @Router /api/lists [get]
-func GetAllLists(ctx context.Context, service list.TodoListService) http.HandlerFunc {
+func GetAllLists(service list.TodoListService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		lists, err := service.GetAll(ctx, userId)
+		params, err := parsePageParams(r)
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		page, err := service.GetAll(r.Context(), userId, params)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
@@ -85,12 +90,11 @@ func GetAllLists(ctx context.Context, service list.TodoListService) http.Handler
 		w.WriteHeader(http.StatusOK)
 
 		response := getAllListsResponse{
-			Data: lists,
-		}
-		if err = json.NewEncoder(w).Encode(response); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
+			Data:       page.Data,
+			NextCursor: page.NextCursor,
+			Total:      page.Total,
 		}
+		encodeJSON(w, r, response)
 	}
 }
 
@@ -103,89 +107,208 @@ func GetAllLists(ctx context.Context, service list.TodoListService) http.Handler
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} todo.ListsItem
-// @Failure 400,404 {object} utility.ErrorResponse
-// @Failure 500 {object} utility.ErrorResponse
-// @Failure default {object} utility.ErrorResponse
+// @Failure 400,404 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
 // @Router /api/lists/:id [get]
-func GetListById(ctx context.Context, service list.TodoListService) http.HandlerFunc {
+func GetListById(service list.TodoListService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		id, err := pathInt(r, "id")
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		list, err := service.GetById(ctx, userId, id)
+		list, err := service.GetById(r.Context(), userId, id)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		if err = json.NewEncoder(w).Encode(list); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		encodeJSON(w, r, list)
 	}
 }
 
-func DeleteList(ctx context.Context, service list.TodoListService) http.HandlerFunc {
+func DeleteList(service list.TodoListService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		id, err := pathInt(r, "id")
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		err = service.Delete(ctx, userId, id)
+		err = service.Delete(r.Context(), userId, id)
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		if err = json.NewEncoder(w).Encode(utility.StatusResponse{Status: "ok"}); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+		encodeJSON(w, r, utility.StatusResponse{Status: "ok"})
+	}
+}
+
+func UpdateList(service list.TodoListService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("UserId").(int)
+
+		id, err := pathInt(r, "id")
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
+
+		var input todo.UpdateListInput
+		if err = decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		if err = service.Update(r.Context(), userId, id, input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		encodeJSON(w, r, utility.StatusResponse{Status: "ok"})
 	}
 }
 
-func UpdateList(ctx context.Context, service list.TodoListService) http.HandlerFunc {
+// ShareList godoc
+// @Summary Share List
+// @Security ApiKeyAuth
+// @Tags lists
+// @Description share the list with another user as owner, editor or viewer
+// @ID share-list
+// @Accept  json
+// @Produce  json
+// @Param id path int true "list id"
+// @Param input body todo.ShareListInput true "collaborator"
+// @Success 200 {object} utility.StatusResponse
+// @Failure 400,403,404,409 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /api/lists/{id}/share [post]
+func ShareList(service list.TodoListService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("UserId").(int)
 
-		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		id, err := pathInt(r, "id")
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		var input todo.UpdateListInput
-		if err = json.NewDecoder(r.Body).Decode(&input); err != nil {
-			utility.NewErrorResponse(w, http.StatusBadRequest, err.Error())
+		var input todo.ShareListInput
+		if err = decodeJSON(r, &input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		if err = service.Update(ctx, userId, id, input); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+		if err = service.Share(r.Context(), userId, id, input); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		if err = json.NewEncoder(w).Encode(utility.StatusResponse{Status: "ok"}); err != nil {
-			utility.NewErrorResponse(w, http.StatusInternalServerError, err.Error())
+		encodeJSON(w, r, utility.StatusResponse{Status: "ok"})
+	}
+}
+
+type getCollaboratorsResponse struct {
+	Data []todo.Collaborator `json:"data"`
+}
+
+// GetCollaborators godoc
+// @Summary Get Collaborators
+// @Security ApiKeyAuth
+// @Tags lists
+// @Description get users the list is shared with and their roles
+// @ID get-collaborators
+// @Produce  json
+// @Param id path int true "list id"
+// @Success 200 {object} getCollaboratorsResponse
+// @Failure 400,403,404 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /api/lists/{id}/collaborators [get]
+func GetCollaborators(service list.TodoListService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("UserId").(int)
+
+		id, err := pathInt(r, "id")
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		collaborators, err := service.GetCollaborators(r.Context(), userId, id)
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		response := getCollaboratorsResponse{
+			Data: collaborators,
+		}
+		encodeJSON(w, r, response)
+	}
+}
+
+// RevokeAccess godoc
+// @Summary Revoke Access
+// @Security ApiKeyAuth
+// @Tags lists
+// @Description remove a collaborator from the list
+// @ID revoke-access
+// @Produce  json
+// @Param id path int true "list id"
+// @Param userId path int true "collaborator user id"
+// @Success 200 {object} utility.StatusResponse
+// @Failure 400,403,404,409 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /api/lists/{id}/collaborators/{userId} [delete]
+func RevokeAccess(service list.TodoListService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("UserId").(int)
+
+		id, err := pathInt(r, "id")
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
+
+		collaboratorId, err := pathInt(r, "userId")
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		if err = service.RevokeAccess(r.Context(), userId, id, collaboratorId); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		encodeJSON(w, r, utility.StatusResponse{Status: "ok"})
 	}
 }
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parsePageParams reads the limit, cursor, sort and order query parameters.
+func parsePageParams(r *http.Request) (todo.PageParams, error) {
+	query := r.URL.Query()
+
+	params := todo.PageParams{
+		Limit:  todo.DefaultPageLimit,
+		Cursor: query.Get("cursor"),
+		SortBy: todo.SortById,
+		Order:  todo.OrderAsc,
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return params, todo.ErrInvalidInput.WithMessage("limit must be a number")
+		}
+		params.Limit = n
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		params.SortBy = sort
+	}
+
+	if order := query.Get("order"); order != "" {
+		params.Order = strings.ToLower(order)
+	}
+
+	return params, params.Validate()
+}
+
+// parseItemFilter reads the done, q, overdue and due_within query parameters.
+func parseItemFilter(r *http.Request) (todo.ItemFilter, error) {
+	query := r.URL.Query()
+
+	filter := todo.ItemFilter{
+		Query: query.Get("q"),
+	}
+
+	if done := query.Get("done"); done != "" {
+		b, err := strconv.ParseBool(done)
+		if err != nil {
+			return filter, todo.ErrInvalidInput.WithMessage("done must be true or false")
+		}
+		filter.Done = &b
+	}
+
+	if overdue := query.Get("overdue"); overdue != "" {
+		b, err := strconv.ParseBool(overdue)
+		if err != nil {
+			return filter, todo.ErrInvalidInput.WithMessage("overdue must be true or false")
+		}
+		filter.Overdue = b
+	}
+
+	if dueWithin := query.Get("due_within"); dueWithin != "" {
+		d, err := time.ParseDuration(dueWithin)
+		if err != nil || d <= 0 {
+			return filter, todo.ErrInvalidInput.WithMessage("due_within must be a positive duration such as 24h")
+		}
+		filter.DueWithin = d
+	}
+
+	return filter, nil
+}
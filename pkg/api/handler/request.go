@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+// decodeJSON reads the request body into v. The decoder names Go types and offsets in its
+// errors, so clients get a fixed message and the error is only logged.
+func decodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return todo.ErrInvalidInput.WithMessage("request body must be a valid JSON object").Wrap(err)
+	}
+
+	return nil
+}
+
+// encodeJSON writes v as the body of a response whose status is already sent. A failure
+// cannot be reported to the client any more, so it is only logged.
+func encodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.FromContext(r.Context()).Warn("failed to write response", zap.Error(err))
+	}
+}
+
+// pathInt reads the integer path variable name, e.g. the id of /lists/{id}.
+func pathInt(r *http.Request, name string) (int, error) {
+	n, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		return 0, todo.ErrInvalidInput.WithMessage(name + " must be a number").Wrap(err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/search"
+	"net/http"
+	"strconv"
+)
+
+type searchResponse struct {
+	Data []todo.SearchHit `json:"data"`
+}
+
+// Search godoc
+// @Summary Search
+// @Security ApiKeyAuth
+// @Tags search
+// @Description full-text search across the lists and items of the user
+// @ID search
+// @Produce  json
+// @Param q query string true "search query, supports quotes, OR and -exclusion"
+// @Param limit query int false "maximum number of hits" default(20) maximum(100)
+// @Success 200 {object} searchResponse
+// @Failure 400 {object} utility.Problem
+// @Failure 500 {object} utility.Problem
+// @Failure default {object} utility.Problem
+// @Router /api/search [get]
+func Search(service search.TodoSearchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("UserId").(int)
+
+		limit := search.DefaultLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil {
+				utility.NewErrorResponse(w, r, http.StatusBadRequest, "limit must be a number")
+				return
+			}
+			limit = n
+		}
+
+		hits, err := service.Search(r.Context(), userId, r.URL.Query().Get("q"), limit)
+		if err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		response := searchResponse{
+			Data: hits,
+		}
+		encodeJSON(w, r, response)
+	}
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"net/http"
+	"strings"
+)
+
+var (
+	corsMethods = strings.Join([]string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}, ", ")
+	corsHeaders = strings.Join([]string{authorizationHeader, utility.ContentType, utility.RequestIdHeader}, ", ")
+)
+
+// CORS lets browsers call the API from the allowed origins of the active config. It wraps
+// the whole router, because preflight requests match no route.
+func CORS(store *config.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			if !allowedOrigin(store.Current().CORS.AllowedOrigins, origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", utility.RequestIdHeader)
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+				w.Header().Set("Access-Control-Allow-Headers", corsHeaders)
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func allowedOrigin(allowed []string, origin string) bool {
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(a, origin) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	store := config.NewStore(config.Config{
+		CORS: config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+	}, config.Options{})
+
+	var reached bool
+	handler := CORS(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	send := func(method, origin, requestMethod string) *httptest.ResponseRecorder {
+		reached = false
+		r := httptest.NewRequest(method, "/api/lists/", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			r.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("preflight", func(t *testing.T) {
+		w := send(http.MethodOptions, "https://APP.example.com", http.MethodPut)
+		if w.Code != http.StatusNoContent || reached {
+			t.Fatalf("status %d, reached the router %v", w.Code, reached)
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") != "https://APP.example.com" ||
+			w.Header().Get("Access-Control-Allow-Methods") == "" || w.Header().Get("Access-Control-Allow-Headers") == "" {
+			t.Fatalf("headers %v", w.Header())
+		}
+	})
+
+	t.Run("allowed origin", func(t *testing.T) {
+		w := send(http.MethodGet, "https://app.example.com", "")
+		if !reached || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Vary") != "Origin" {
+			t.Fatalf("reached %v, headers %v", reached, w.Header())
+		}
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		w := send(http.MethodOptions, "https://evil.example.com", http.MethodDelete)
+		if !reached || w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Methods") != "" {
+			t.Fatalf("reached %v, headers %v", reached, w.Header())
+		}
+	})
+
+	t.Run("same origin", func(t *testing.T) {
+		w := send(http.MethodGet, "", "")
+		if !reached || w.Header().Get("Vary") != "" {
+			t.Fatalf("reached %v, headers %v", reached, w.Header())
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"net/http"
+)
+
+// Feature hides routes behind a flag of the active config: while it is off they answer 404.
+func Feature(store *config.Store, enabled func(config.FeatureFlags) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled(store.Current().Features) {
+				utility.NewErrorResponse(w, r, http.StatusNotFound, "feature is disabled")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/metrics"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Route)
+	router.HandleFunc("/api/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := RequestLog(router)
+
+	for _, path := range []string{"/api/items/1", "/api/items/2", "/nowhere/3"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	for _, method := range []string{"FOO1", "FOO2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/api/items/3", nil))
+	}
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`todo_http_requests_total{method="GET",route="/api/items/{id}",status="418"} 2`,
+		`todo_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`todo_http_request_duration_seconds_count{method="GET",route="/api/items/{id}"} 2`,
+		`todo_http_requests_total{method="other",route="/api/items/{id}",status="418"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("%s missing from\n%s", want, body)
+		}
+	}
+
+	if strings.Contains(body, "/api/items/1") || strings.Contains(body, "/nowhere/3") {
+		t.Fatal("raw paths used as label values")
+	}
+	if strings.Contains(body, "FOO1") {
+		t.Fatal("raw methods used as label values")
+	}
+}
@@ -27,25 +27,39 @@ func (m *UserAuthMiddleware) UserAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		header := r.Header.Get(authorizationHeader)
 		if header == "" {
-			utility.NewErrorResponse(w, http.StatusUnauthorized, "empty auth header")
+			utility.NewErrorResponse(w, r, http.StatusUnauthorized, "empty auth header")
 			return
 		}
 
 		headerParts := strings.Split(header, " ")
 		if len(headerParts) != 2 {
-			utility.NewErrorResponse(w, http.StatusUnauthorized, "invalid auth header")
+			utility.NewErrorResponse(w, r, http.StatusUnauthorized, "invalid auth header")
 			return
 		}
 
-		userId, err := m.service.ParseToken(headerParts[1])
+		userId, err := m.service.ParseToken(r.Context(), headerParts[1])
 		if err != nil {
-			utility.NewErrorResponse(w, http.StatusUnauthorized, err.Error())
+			utility.NewServiceErrorResponse(w, r, err)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), UserCtx, userId)
+		ctx := context.WithValue(withUser(r.Context(), userId), UserCtx, userId)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Admin lets through administrators only. It goes after UserAuth.
+func (m *UserAuthMiddleware) Admin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(UserCtx).(int)
+
+		if err := m.service.RequireAdmin(r.Context(), userId); err != nil {
+			utility.NewServiceErrorResponse(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
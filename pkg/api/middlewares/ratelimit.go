@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"golang.org/x/time/rate"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idleClient is how long a client keeps its bucket after its last request.
+const idleClient = 3 * time.Minute
+
+type rateClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter gives every client IP a token bucket sized by the active config. A change of
+// the limits starts every client with a full bucket.
+type RateLimiter struct {
+	store     *config.Store
+	mu        sync.Mutex
+	limits    config.RateLimitConfig
+	clients   map[string]*rateClient
+	lastSweep time.Time
+}
+
+func NewRateLimiter(store *config.Store) *RateLimiter {
+	return &RateLimiter{
+		store:   store,
+		clients: make(map[string]*rateClient),
+	}
+}
+
+// RateLimit rejects requests over the limit with 429. Clients are told apart by the
+// connection address, so behind a proxy all of them share the proxy's bucket.
+func (l *RateLimiter) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "1")
+			utility.NewErrorResponse(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimiter) allow(ip string) bool {
+	limits := l.store.Current().RateLimit
+	if !limits.Enabled {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if limits != l.limits {
+		l.limits = limits
+		l.clients = make(map[string]*rateClient)
+	}
+
+	if now.Sub(l.lastSweep) > time.Minute {
+		for key, c := range l.clients {
+			if now.Sub(c.lastSeen) > idleClient {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &rateClient{limiter: rate.NewLimiter(rate.Limit(limits.RequestsPerSecond), limits.Burst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+
+	return c.limiter.AllowN(now, 1)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	store := config.NewStore(config.Config{
+		RateLimit: config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.001, Burst: 2},
+	}, config.Options{})
+	handler := NewRateLimiter(store).RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/lists/", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := send("10.0.0.1:1000"); w.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst: %d", i, w.Code)
+		}
+	}
+
+	w := send("10.0.0.1:1001")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("over the limit: %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	if w = send("10.0.0.2:1000"); w.Code != http.StatusOK {
+		t.Fatalf("another client: %d", w.Code)
+	}
+}
+
+func TestRateLimitFollowsConfig(t *testing.T) {
+	store := config.NewStore(config.Config{}, config.Options{})
+	handler := NewRateLimiter(store).RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("disabled limit rejected request %d: %d", i, w.Code)
+		}
+	}
+}
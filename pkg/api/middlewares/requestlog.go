@@ -0,0 +1,150 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRequestIdLength bounds the ids accepted from clients, which end up in every log entry.
+const maxRequestIdLength = 128
+
+// requestInfo is filled in further down the chain, where the matched route and the user
+// are known, and read by RequestLog once the request is served.
+type requestInfo struct {
+	route  string
+	userId int
+}
+
+type requestInfoKey struct{}
+
+// RequestLog gives every request an id, taken from X-Request-Id when the client or a proxy
+// sent a usable one, puts a logger adding it to every entry into the context and logs the
+// request once it is served, also recording it in the HTTP metrics. It wraps the whole
+// router, so rejected and unmatched requests are logged too.
+func RequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+
+		requestId := r.Header.Get(utility.RequestIdHeader)
+		if !validRequestId(requestId) {
+			requestId = newRequestId()
+		}
+		w.Header().Set(utility.RequestIdHeader, requestId)
+
+		info := &requestInfo{}
+		logger := zap.L().With(zap.String("request_id", requestId))
+
+		ctx := context.WithValue(r.Context(), requestInfoKey{}, info)
+		ctx = logging.WithRequestId(logging.WithLogger(ctx, logger), requestId)
+
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		latency := time.Since(started)
+		observeRequest(r.Method, info.route, recorder.status, latency)
+
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("route", info.route),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", recorder.status),
+			zap.Duration("latency", latency),
+			zap.Int("bytes", recorder.bytes),
+		}
+		if info.userId != 0 {
+			fields = append(fields, zap.Int("user_id", info.userId))
+		}
+
+		// probes come every few seconds and would drown the other requests
+		level := zapcore.InfoLevel
+		if strings.HasPrefix(info.route, "/health/") {
+			level = zapcore.DebugLevel
+		}
+
+		logger.Log(level, "request served", fields...)
+	})
+}
+
+// Route records the path template of the matched route, e.g. /api/lists/{id}, for the
+// access log. It is a router middleware, so it runs only once a route matched.
+func Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				info.route, _ = route.GetPathTemplate()
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withUser adds the authenticated user to the access log and to the entries logged
+// through the context from here on.
+func withUser(ctx context.Context, userId int) context.Context {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userId = userId
+	}
+
+	return logging.WithLogger(ctx, logging.FromContext(ctx).With(zap.Int("user_id", userId)))
+}
+
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+
+	for _, c := range requestId {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the Flusher and Hijacker of the wrapped writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestLog(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	router := mux.NewRouter()
+	router.Use(Route)
+	router.HandleFunc("/api/lists/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := withUser(r.Context(), 7)
+		logging.FromContext(ctx).Info("looking up the list")
+		utility.NewErrorResponse(w, r.WithContext(ctx), http.StatusNotFound, "list not found")
+	})
+	handler := RequestLog(router)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/lists/5", nil)
+	r.Header.Set(utility.RequestIdHeader, "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if id := w.Header().Get(utility.RequestIdHeader); id != "abc-123" {
+		t.Fatalf("response request id %q", id)
+	}
+
+	var problem utility.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.RequestId != "abc-123" {
+		t.Fatalf("problem %+v: %v", problem, err)
+	}
+
+	for _, message := range []string{"looking up the list", "request rejected"} {
+		entries := logs.FilterMessage(message).FilterField(zap.String("request_id", "abc-123")).FilterField(zap.Int("user_id", 7))
+		if entries.Len() != 1 {
+			t.Fatalf("%q not logged with the request and user ids: %+v", message, logs.All())
+		}
+	}
+
+	served := logs.FilterMessage("request served").All()
+	if len(served) != 1 {
+		t.Fatalf("%d access log entries", len(served))
+	}
+	fields := served[0].ContextMap()
+	if fields["route"] != "/api/lists/{id}" || fields["status"] != int64(http.StatusNotFound) || fields["user_id"] != int64(7) || fields["bytes"].(int64) == 0 {
+		t.Fatalf("access log %+v", fields)
+	}
+}
+
+func TestRequestLogReplacesUnusableIds(t *testing.T) {
+	handler := RequestLog(http.NotFoundHandler())
+
+	for _, id := range []string{"", "has spaces", string(make([]byte, maxRequestIdLength+1))} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(utility.RequestIdHeader, id)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if got := w.Header().Get(utility.RequestIdHeader); got == id || len(got) != 32 {
+			t.Fatalf("request id %q replaced by %q", id, got)
+		}
+	}
+}
+
+func TestRequestLogKeepsFlusher(t *testing.T) {
+	handler := RequestLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush: %v", err)
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !w.Flushed {
+		t.Fatal("response was not flushed")
+	}
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/utility"
+	"net/http"
+	"strings"
+)
+
+// BearerToken lets through requests with "Authorization: Bearer <token>". The token is compared
+// in constant time, and an empty token rejects every request, so leaving it unset never opens
+// the route.
+func BearerToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get(authorizationHeader), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				utility.NewErrorResponse(w, r, http.StatusUnauthorized, "invalid token")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
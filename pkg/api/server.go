@@ -2,75 +2,150 @@ package api
 
 import (
 	"context"
+	"errors"
+	"expvar"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/handler"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/api/middlewares"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/metrics"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/auth"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/item"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/list"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/logs"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/search"
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"go.uber.org/zap"
+	"net"
 	"net/http"
-	"time"
-)
-
-const (
-	maxHeaderBytes = 1 << 20 // 1 MB
-	readTimeout    = 10 * time.Second
-	writeTimeout   = 10 * time.Second
 )
 
 type Server struct {
-	httpServer *http.Server
-	router     *mux.Router
-	subRouter  *mux.Router
+	httpServer  *http.Server
+	router      *mux.Router
+	authRouter  *mux.Router
+	subRouter   *mux.Router
+	adminRouter *mux.Router
+	store       *config.Store
 }
 
-func NewServer(middleware *middlewares.UserAuthMiddleware) *Server {
+// NewServer reads the server settings and the metrics token once; the CORS origins, rate limits and feature flags
+// are read from the store on every request, so they follow config reloads.
+func NewServer(store *config.Store, middleware *middlewares.UserAuthMiddleware) *Server {
+	current := store.Current()
+	cfg := current.Server
 	router := mux.NewRouter()
+	router.Use(middlewares.Route)
+
+	swagger := middlewares.Feature(store, func(f config.FeatureFlags) bool { return f.Swagger })
+	router.PathPrefix("/swagger/").Handler(swagger(httpSwagger.WrapHandler))
 
-	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	metricsFeature := middlewares.Feature(store, func(f config.FeatureFlags) bool { return f.Metrics })
+	metricsToken := middlewares.BearerToken(current.Metrics.Token)
+	router.Handle("/metrics", metricsFeature(metricsToken(metrics.Handler()))).Methods(http.MethodGet)
+
+	authRouter := router.PathPrefix("/auth").Subrouter()
+	authRouter.Use(middlewares.Timeout(cfg.AuthTimeout))
 
 	api := router.PathPrefix("/api").Subrouter()
-	api.Use(middleware.UserAuth)
+	api.Use(middlewares.Timeout(cfg.RequestTimeout), middleware.UserAuth)
+
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(middlewares.Timeout(cfg.RequestTimeout), middleware.UserAuth, middleware.Admin)
+	// the counters include raw backend errors and the command line, so only administrators see them
+	admin.Handle("/debug/vars", expvar.Handler()).Methods(http.MethodGet)
 
 	return &Server{
 		httpServer: &http.Server{
-			Addr:           ":8000",
-			MaxHeaderBytes: maxHeaderBytes,
-			ReadTimeout:    readTimeout,
-			WriteTimeout:   writeTimeout,
-			Handler:        router,
+			Addr:           cfg.Addr(),
+			MaxHeaderBytes: cfg.MaxHeaderBytes,
+			ReadTimeout:    cfg.ReadTimeout,
+			WriteTimeout:   cfg.WriteTimeout,
+			Handler:        middlewares.RequestLog(middlewares.CORS(store)(middlewares.NewRateLimiter(store).RateLimit(router))),
 		},
-		router:    router,
-		subRouter: api,
+		router:      router,
+		authRouter:  authRouter,
+		subRouter:   api,
+		adminRouter: admin,
+		store:       store,
 	}
 }
 
-func (s *Server) Run() error {
-	return s.httpServer.ListenAndServe()
+// Listen binds the configured address, so a taken port is reported before the server
+// counts as started.
+func (s *Server) Listen() (net.Listener, error) {
+	return net.Listen("tcp", s.httpServer.Addr)
 }
 
+// Serve handles the connections of l until Shutdown, after which it returns nil.
+func (s *Server) Serve(l net.Listener) error {
+	if err := s.httpServer.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Handler returns the router with its middlewares, so the API can be served without
+// listening on a port.
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// Shutdown stops accepting connections and waits for the requests in flight until ctx is done.
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
 
+// HandleHealth adds the probes of orchestrators: liveness answers as long as the process
+// serves HTTP, readiness only while ready returns nil.
+func (s *Server) HandleHealth(ready handler.ReadinessFunc) {
+	timeout := middlewares.Timeout(s.store.Current().Server.RequestTimeout)
+	s.router.Handle("/health/live", handler.Live()).Methods(http.MethodGet)
+	s.router.Handle("/health/ready", timeout(handler.Ready(ready))).Methods(http.MethodGet)
+}
+
 func (s *Server) HandleAuth(service auth.AuthorizationService) {
-	s.router.HandleFunc("/auth/sign-up/", handler.SignUp(service)).Methods(http.MethodPost)
-	s.router.HandleFunc("/auth/sign-in/", handler.SignIn(service)).Methods(http.MethodPost)
+	s.authRouter.HandleFunc("/sign-up/", handler.SignUp(service)).Methods(http.MethodPost)
+	s.authRouter.HandleFunc("/sign-in/", handler.SignIn(service)).Methods(http.MethodPost)
+	s.authRouter.HandleFunc("/refresh/", handler.Refresh(service)).Methods(http.MethodPost)
+	s.authRouter.HandleFunc("/logout/", handler.Logout(service)).Methods(http.MethodPost)
+	s.router.HandleFunc("/.well-known/jwks.json", handler.JWKS(service)).Methods(http.MethodGet)
+}
+
+func (s *Server) HandleLists(service list.TodoListService) {
+	s.subRouter.HandleFunc("/lists/", handler.CreateList(service)).Methods(http.MethodPost)
+	s.subRouter.HandleFunc("/lists/", handler.GetAllLists(service)).Methods(http.MethodGet)
+	s.subRouter.HandleFunc("/lists/{id}", handler.GetListById(service)).Methods(http.MethodGet)
+	s.subRouter.HandleFunc("/lists/{id}", handler.DeleteList(service)).Methods(http.MethodDelete)
+	s.subRouter.HandleFunc("/lists/{id}", handler.UpdateList(service)).Methods(http.MethodPut)
+	s.subRouter.HandleFunc("/lists/{id}/share", handler.ShareList(service)).Methods(http.MethodPost)
+	s.subRouter.HandleFunc("/lists/{id}/collaborators", handler.GetCollaborators(service)).Methods(http.MethodGet)
+	s.subRouter.HandleFunc("/lists/{id}/collaborators/{userId}", handler.RevokeAccess(service)).Methods(http.MethodDelete)
+}
+
+func (s *Server) HandleItems(service item.TodoItemService) {
+	s.subRouter.HandleFunc("/lists/{id}/items/", handler.CreateItem(service)).Methods(http.MethodPost)
+	s.subRouter.HandleFunc("/lists/{id}/items/", handler.GetAllItems(service)).Methods(http.MethodGet)
+	s.subRouter.HandleFunc("/items/{id}", handler.GetItemById(service)).Methods(http.MethodGet)
+	s.subRouter.HandleFunc("/items/{id}", handler.DeleteItem(service)).Methods(http.MethodDelete)
+	s.subRouter.HandleFunc("/items/{id}", handler.UpdateItem(service)).Methods(http.MethodPut)
+}
+
+func (s *Server) HandleSearch(service search.TodoSearchService) {
+	// nested deadlines take the earliest one, so this shortens the deadline of the subrouter
+	search := middlewares.Feature(s.store, func(f config.FeatureFlags) bool { return f.Search })
+	timeout := middlewares.Timeout(s.store.Current().Server.SearchTimeout)
+	s.subRouter.Handle("/search", search(timeout(handler.Search(service)))).Methods(http.MethodGet)
 }
 
-func (s *Server) HandleLists(ctx context.Context, service list.TodoListService) {
-	s.subRouter.HandleFunc("/lists/", handler.CreateList(ctx, service)).Methods(http.MethodPost)
-	s.subRouter.HandleFunc("/lists/", handler.GetAllLists(ctx, service)).Methods(http.MethodGet)
-	s.subRouter.HandleFunc("/lists/{id}", handler.GetListById(ctx, service)).Methods(http.MethodGet)
-	s.subRouter.HandleFunc("/lists/{id}", handler.DeleteList(ctx, service)).Methods(http.MethodDelete)
-	s.subRouter.HandleFunc("/lists/{id}", handler.UpdateList(ctx, service)).Methods(http.MethodPut)
+// HandleLogLevel lets administrators read and change the log level at runtime.
+func (s *Server) HandleLogLevel(level zap.AtomicLevel) {
+	s.adminRouter.HandleFunc("/log/level", handler.GetLogLevel(level)).Methods(http.MethodGet)
+	s.adminRouter.HandleFunc("/log/level", handler.SetLogLevel(level)).Methods(http.MethodPut)
 }
 
-func (s *Server) HandleItems(ctx context.Context, service item.TodoItemService) {
-	s.subRouter.HandleFunc("/lists/{id}/items/", handler.CreateItem(ctx, service)).Methods(http.MethodPost)
-	s.subRouter.HandleFunc("/lists/{id}/items/", handler.GetAllItems(ctx, service)).Methods(http.MethodGet)
-	s.subRouter.HandleFunc("/items/{id}", handler.GetItemById(ctx, service)).Methods(http.MethodGet)
-	s.subRouter.HandleFunc("/items/{id}", handler.DeleteItem(ctx, service)).Methods(http.MethodDelete)
-	s.subRouter.HandleFunc("/items/{id}", handler.UpdateItem(ctx, service)).Methods(http.MethodPut)
+// HandleLogs lets administrators read the logs stored in MongoDB.
+func (s *Server) HandleLogs(service logs.LogService) {
+	s.adminRouter.HandleFunc("/logs", handler.GetLogs(service)).Methods(http.MethodGet)
 }
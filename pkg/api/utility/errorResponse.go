@@ -1,37 +1,123 @@
 package utility
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
 )
 
 const (
-	ContentType     = "Content-Type"
-	ApplicationJSON = "application/json"
+	ContentType        = "Content-Type"
+	ApplicationJSON    = "application/json"
+	ApplicationProblem = "application/problem+json"
+	RequestIdHeader    = "X-Request-Id"
 )
 
 type StatusResponse struct {
 	Status string `json:"status"`
 }
 
-type ErrorResponse struct {
-	Message string `json:"message"`
+// Problem is an RFC 7807 problem details object. Code is a stable machine-readable
+// extension member; Errors lists the rejected fields of a failed validation.
+type Problem struct {
+	Type      string               `json:"type"`
+	Title     string               `json:"title"`
+	Status    int                  `json:"status"`
+	Detail    string               `json:"detail,omitempty"`
+	Instance  string               `json:"instance,omitempty"`
+	RequestId string               `json:"request_id,omitempty"`
+	Code      string               `json:"code"`
+	Errors    []apperror.Violation `json:"errors,omitempty"`
 }
 
-func NewErrorResponse(w http.ResponseWriter, statusCode int, message string) {
-	zap.Error(errors.New(message))
+func NewErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	writeProblem(w, r, Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: message,
+		Code:   statusCodeToCode(statusCode),
+	}, nil)
+}
+
+// NewServiceErrorResponse translates an error returned by a service. Domain errors get
+// their status code and error code, anything else is logged and reported as a bare 500,
+// so driver messages never reach clients.
+func NewServiceErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeProblem(w, r, newProblem(http.StatusGatewayTimeout, "timeout", "request timed out"), err)
+		return
+	}
+
+	appErr, ok := apperror.As(err)
+	if !ok || appErr.Kind == apperror.Internal {
+		writeProblem(w, r, newProblem(http.StatusInternalServerError, "internal", ""), err)
+		return
+	}
+
+	problem := newProblem(kindStatus[appErr.Kind], appErr.Code, appErr.Message)
+	problem.Errors = appErr.Violations
+
+	writeProblem(w, r, problem, err)
+}
+
+var kindStatus = map[apperror.Kind]int{
+	apperror.NotFound:     http.StatusNotFound,
+	apperror.Conflict:     http.StatusConflict,
+	apperror.Validation:   http.StatusBadRequest,
+	apperror.Forbidden:    http.StatusForbidden,
+	apperror.Unauthorized: http.StatusUnauthorized,
+}
+
+func newProblem(statusCode int, code, detail string) Problem {
+	return Problem{
+		Type:   "/problems/" + code,
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// statusCodeToCode turns e.g. 400 into "bad_request" for errors reported without a domain error.
+func statusCodeToCode(statusCode int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(statusCode)), " ", "_")
+}
 
-	errResponse := ErrorResponse{Message: message}
+func writeProblem(w http.ResponseWriter, r *http.Request, problem Problem, cause error) {
+	problem.Instance = r.URL.Path
+	problem.RequestId = logging.RequestId(r.Context())
+
+	fields := []zap.Field{
+		zap.Int("status", problem.Status),
+		zap.String("code", problem.Code),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+	}
+	if cause != nil {
+		fields = append(fields, zap.Error(cause))
+	} else if problem.Detail != "" {
+		fields = append(fields, zap.String("detail", problem.Detail))
+	}
+
+	if problem.Status >= http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error("request failed", fields...)
+	} else {
+		logging.FromContext(r.Context()).Info("request rejected", fields...)
+	}
 
-	jsonErrResponse, err := json.Marshal(errResponse)
+	jsonProblem, err := json.Marshal(problem)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set(ContentType, ApplicationJSON)
-	w.WriteHeader(statusCode)
-	w.Write(jsonErrResponse)
+	w.Header().Set(ContentType, ApplicationProblem)
+	w.WriteHeader(problem.Status)
+	w.Write(jsonProblem)
 }
@@ -0,0 +1,113 @@
+// Package apperror defines the errors the repositories and services return for failures
+// caused by the request rather than by the server, so the API layer can tell them apart.
+package apperror
+
+import "errors"
+
+type Kind int
+
+const (
+	Internal Kind = iota
+	NotFound
+	Conflict
+	Validation
+	Forbidden
+	Unauthorized
+)
+
+// Error carries a stable machine-readable code and a message that is safe to show to
+// clients. The cause is kept for logs only.
+type Error struct {
+	Kind       Kind
+	Code       string
+	Message    string
+	Violations []Violation
+	cause      error
+}
+
+// Violation describes why the value of a single input field was rejected.
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func NewNotFound(code, message string) *Error {
+	return New(NotFound, code, message)
+}
+
+func NewConflict(code, message string) *Error {
+	return New(Conflict, code, message)
+}
+
+func NewValidation(code, message string) *Error {
+	return New(Validation, code, message)
+}
+
+func NewForbidden(code, message string) *Error {
+	return New(Forbidden, code, message)
+}
+
+func NewUnauthorized(code, message string) *Error {
+	return New(Unauthorized, code, message)
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors by code, so errors.Is(err, ErrListNotFound) holds for every copy
+// made from ErrListNotFound by Wrap or WithMessage.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of the error caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.cause = err
+	return &c
+}
+
+// WithMessage returns a copy of the error with a more specific message.
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithViolations returns a copy of the error listing the rejected fields.
+func (e *Error) WithViolations(violations ...Violation) *Error {
+	c := *e
+	c.Violations = violations
+	return &c
+}
+
+// As returns the first *Error in the chain of err.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// KindOf returns Internal for errors that are not an *Error.
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+
+	return Internal
+}
@@ -0,0 +1,170 @@
+// Package lifecycle starts the parts of the application in order and stops them in reverse,
+// so nothing is closed while something started after it still uses it.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"time"
+)
+
+var ErrNotReady = errors.New("application is not ready")
+
+type component struct {
+	name  string
+	start func(ctx context.Context) error
+	stop  func(ctx context.Context) error
+}
+
+// Check tells whether a dependency can serve requests, e.g. by pinging it.
+type Check func(ctx context.Context) error
+
+// Manager runs the components added to it. It logs through the global zap logger, which
+// one of the components usually replaces.
+type Manager struct {
+	components []component
+	started    []component
+	checks     map[string]Check
+	ready      atomic.Bool
+	failed     chan error
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		checks: make(map[string]Check),
+		failed: make(chan error, 1),
+	}
+}
+
+// Add appends a component. start must not block; either function may be nil.
+func (m *Manager) Add(name string, start, stop func(ctx context.Context) error) {
+	m.components = append(m.components, component{name: name, start: start, stop: stop})
+}
+
+// AddBackground appends a component running until its context is cancelled on stop.
+// An error returned before that stops the application.
+func (m *Manager) AddBackground(name string, run func(ctx context.Context) error) {
+	var (
+		cancel context.CancelFunc
+		done   = make(chan struct{})
+	)
+
+	m.Add(name, func(ctx context.Context) error {
+		var runCtx context.Context
+		runCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+		m.Go(name, func() error {
+			defer close(done)
+			return run(runCtx)
+		})
+		return nil
+	}, func(ctx context.Context) error {
+		cancel()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Go runs fn in the background. An error returned by it stops the application.
+func (m *Manager) Go(name string, fn func() error) {
+	go func() {
+		if err := fn(); err != nil {
+			select {
+			case m.failed <- fmt.Errorf("%s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// AddCheck registers a readiness check.
+func (m *Manager) AddCheck(name string, check Check) {
+	m.checks[name] = check
+}
+
+// Ready reports whether every component is started, the application is not shutting down
+// and every check passes.
+func (m *Manager) Ready(ctx context.Context) error {
+	if !m.ready.Load() {
+		return ErrNotReady
+	}
+
+	for name, check := range m.checks {
+		if err := check(ctx); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Run starts the components in order and blocks until ctx is done or a component fails.
+// Then it stops the started components in reverse order, giving all of them together
+// shutdownTimeout.
+func (m *Manager) Run(ctx context.Context, shutdownTimeout time.Duration) error {
+	startErr := m.start(ctx)
+
+	var cause error
+	if startErr == nil {
+		m.ready.Store(true)
+		zap.S().Info("application is ready")
+
+		select {
+		case <-ctx.Done():
+			zap.S().Info("shutting down")
+		case cause = <-m.failed:
+			zap.S().Errorw("shutting down after a failure", "error", cause)
+		}
+
+		m.ready.Store(false)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	return errors.Join(startErr, cause, m.stop(stopCtx))
+}
+
+func (m *Manager) start(ctx context.Context) error {
+	for _, c := range m.components {
+		if c.start != nil {
+			if err := c.start(ctx); err != nil {
+				return fmt.Errorf("failed to start %s: %w", c.name, err)
+			}
+		}
+
+		m.started = append(m.started, c)
+		zap.S().Infow("started", "component", c.name)
+	}
+
+	return nil
+}
+
+func (m *Manager) stop(ctx context.Context) error {
+	var errs []error
+	for i := len(m.started) - 1; i >= 0; i-- {
+		c := m.started[i]
+		if c.stop == nil {
+			continue
+		}
+
+		began := time.Now()
+		if err := c.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", c.name, err))
+			continue
+		}
+
+		zap.S().Infow("stopped", "component", c.name, "took", time.Since(began))
+	}
+	m.started = nil
+
+	return errors.Join(errs...)
+}
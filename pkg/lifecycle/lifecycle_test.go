@@ -0,0 +1,100 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunStopsInReverseOrder(t *testing.T) {
+	m := NewManager()
+	var events []string
+
+	for _, name := range []string{"postgres", "redis", "http server"} {
+		m.Add(name, func(ctx context.Context) error {
+			events = append(events, "start "+name)
+			return nil
+		}, func(ctx context.Context) error {
+			events = append(events, "stop "+name)
+			return nil
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Go("canceller", func() error {
+		for m.Ready(ctx) != nil {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		return nil
+	})
+
+	if err := m.Run(ctx, time.Second); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := []string{"start postgres", "start redis", "start http server", "stop http server", "stop redis", "stop postgres"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events %v, want %v", events, want)
+	}
+
+	if err := m.Ready(ctx); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("ready after run: %v", err)
+	}
+}
+
+func TestRunStopsOnlyStartedComponents(t *testing.T) {
+	m := NewManager()
+	failed := errors.New("connection refused")
+	var stopped []string
+
+	m.Add("postgres", nil, func(ctx context.Context) error {
+		stopped = append(stopped, "postgres")
+		return nil
+	})
+	m.Add("redis", func(ctx context.Context) error { return failed }, func(ctx context.Context) error {
+		stopped = append(stopped, "redis")
+		return nil
+	})
+
+	if err := m.Run(context.Background(), time.Second); !errors.Is(err, failed) {
+		t.Fatalf("run: %v", err)
+	}
+
+	if !reflect.DeepEqual(stopped, []string{"postgres"}) {
+		t.Fatalf("stopped %v", stopped)
+	}
+}
+
+func TestBackgroundFailureStopsApplication(t *testing.T) {
+	m := NewManager()
+	failed := errors.New("watcher failed")
+	stopped := false
+
+	m.Add("postgres", nil, func(ctx context.Context) error {
+		stopped = true
+		return nil
+	})
+	m.AddBackground("watcher", func(ctx context.Context) error { return failed })
+
+	if err := m.Run(context.Background(), time.Second); !errors.Is(err, failed) {
+		t.Fatalf("run: %v", err)
+	}
+
+	if !stopped {
+		t.Fatal("postgres is not stopped")
+	}
+}
+
+func TestReadyRunsChecks(t *testing.T) {
+	m := NewManager()
+	down := errors.New("down")
+	m.AddCheck("redis", func(ctx context.Context) error { return down })
+	m.ready.Store(true)
+
+	if err := m.Ready(context.Background()); !errors.Is(err, down) {
+		t.Fatalf("ready: %v", err)
+	}
+}
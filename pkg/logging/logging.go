@@ -0,0 +1,143 @@
+// Package logging builds the application logger from the sinks enabled in config.LogConfig.
+package logging
+
+import (
+	"errors"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"os"
+	"time"
+)
+
+var encoderConfig = zapcore.EncoderConfig{
+	TimeKey:        "timestamp",
+	LevelKey:       "level",
+	NameKey:        "logger",
+	CallerKey:      "caller",
+	MessageKey:     "msg",
+	StacktraceKey:  "stacktrace",
+	LineEnding:     zapcore.DefaultLineEnding,
+	EncodeTime:     zapcore.ISO8601TimeEncoder,
+	EncodeLevel:    zapcore.LowercaseLevelEncoder,
+	EncodeDuration: zapcore.SecondsDurationEncoder,
+	EncodeCaller:   zapcore.ShortCallerEncoder,
+}
+
+// Logger writes to a tee of the console, JSON and file sinks. The Mongo sink needs a
+// connection, so it is added later by WithMongo.
+type Logger struct {
+	*zap.Logger
+	// Level is shared by every sink; setting it takes effect at once.
+	Level zap.AtomicLevel
+	cfg   config.LogConfig
+	cores []zapcore.Core
+	files []*lumberjack.Logger
+}
+
+func New(cfg config.LogConfig) (*Logger, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Logger{Level: level, cfg: cfg}
+
+	if cfg.Console.Enabled {
+		encoder := encoderConfig
+		encoder.EncodeLevel = zapcore.CapitalLevelEncoder
+		if err = l.addCore(cfg.Console.Level, zapcore.NewConsoleEncoder(encoder), zapcore.Lock(os.Stderr)); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.JSON.Enabled {
+		if err = l.addCore(cfg.JSON.Level, zapcore.NewJSONEncoder(encoderConfig), zapcore.Lock(os.Stdout)); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.File.Enabled {
+		file := &lumberjack.Logger{
+			Filename:   cfg.File.Path,
+			MaxSize:    cfg.File.MaxSizeMB,
+			MaxBackups: cfg.File.MaxBackups,
+			MaxAge:     cfg.File.MaxAgeDays,
+			Compress:   cfg.File.Compress,
+		}
+		l.files = append(l.files, file)
+
+		if err = l.addCore(cfg.File.Level, zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(file)); err != nil {
+			return nil, err
+		}
+	}
+
+	l.Logger = l.build()
+	return l, nil
+}
+
+// WithMongo returns a logger writing to the sink as well. It returns the logger without it
+// when the Mongo sink is disabled.
+func (l *Logger) WithMongo(sink *MongoDBSink) (*zap.Logger, error) {
+	if !l.cfg.Mongo.Enabled {
+		return l.Logger, nil
+	}
+
+	level, err := l.levelFor(l.cfg.Mongo.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.build(NewMongoDBCore(sink, level)), nil
+}
+
+// Close flushes the sinks and closes the log files. The Mongo sink is closed on its own.
+func (l *Logger) Close() error {
+	// stdout and stderr cannot be synced on every platform, which is not worth reporting
+	_ = l.Logger.Sync()
+
+	var errs []error
+	for _, file := range l.files {
+		errs = append(errs, file.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l *Logger) addCore(sinkLevel string, encoder zapcore.Encoder, out zapcore.WriteSyncer) error {
+	level, err := l.levelFor(sinkLevel)
+	if err != nil {
+		return err
+	}
+
+	l.cores = append(l.cores, zapcore.NewCore(encoder, out, level))
+	return nil
+}
+
+// levelFor lets through the entries enabled by both Level and the level of the sink, which
+// may be empty to follow Level alone.
+func (l *Logger) levelFor(sinkLevel string) (zapcore.LevelEnabler, error) {
+	if sinkLevel == "" {
+		return l.Level, nil
+	}
+
+	min, err := zapcore.ParseLevel(sinkLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	return zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return level >= min && l.Level.Enabled(level)
+	}), nil
+}
+
+func (l *Logger) build(extra ...zapcore.Core) *zap.Logger {
+	core := zapcore.NewTee(append(l.cores[:len(l.cores):len(l.cores)], extra...)...)
+
+	if l.cfg.Sampling.Thereafter > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, l.cfg.Sampling.Initial, l.cfg.Sampling.Thereafter)
+	}
+
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel), zap.ErrorOutput(zapcore.Lock(os.Stderr)))
+}
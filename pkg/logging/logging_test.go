@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSinkLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := New(config.LogConfig{
+		Level: "debug",
+		File:  config.LogFileConfig{Enabled: true, Level: "info", Path: path},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	logger.Debug("below the file level")
+	logger.Info("written", zap.Int("list_id", 7))
+
+	logger.Level.SetLevel(zap.WarnLevel)
+	logger.Info("below the shared level")
+	logger.Warn("written too")
+
+	if err = logger.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"list_id":7`) || !strings.Contains(lines[1], "written too") {
+		t.Fatalf("log file:\n%s", content)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := New(config.LogConfig{
+		Level:    "info",
+		Sampling: config.LogSamplingConfig{Initial: 2, Thereafter: 10},
+		File:     config.LogFileConfig{Enabled: true, Path: path},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	for i := 0; i < 22; i++ {
+		logger.Info("same message")
+	}
+	if err = logger.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	// the first 2, then the 12th and the 22nd
+	if n := strings.Count(string(content), "same message"); n != 4 {
+		t.Fatalf("%d entries kept", n)
+	}
+}
@@ -0,0 +1,393 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSinkClosed is returned by Flush after Close.
+var ErrSinkClosed = errors.New("log sink is closed")
+
+// sinkVars is published as "log_sink" on /admin/debug/vars.
+var sinkVars = expvar.NewMap("log_sink")
+
+// currentSink is the sink the metrics report on, the one created last.
+var currentSink atomic.Pointer[MongoDBSink]
+
+func init() {
+	sinkMetric := func(name, help string, value func(SinkStatsSnapshot) float64) prometheus.Collector {
+		opts := prometheus.Opts{Namespace: metrics.Namespace, Subsystem: "log_sink", Name: name, Help: help}
+		read := func() float64 {
+			if sink := currentSink.Load(); sink != nil {
+				return value(sink.Stats())
+			}
+			return 0
+		}
+
+		if strings.HasSuffix(name, "_total") {
+			return prometheus.NewCounterFunc(prometheus.CounterOpts(opts), read)
+		}
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts), read)
+	}
+
+	metrics.MustRegister(
+		sinkMetric("queued", "Log entries waiting to be written to MongoDB.",
+			func(s SinkStatsSnapshot) float64 { return float64(s.Queued) }),
+		sinkMetric("capacity", "Size of the queue of the MongoDB log sink.",
+			func(s SinkStatsSnapshot) float64 { return float64(s.Capacity) }),
+		sinkMetric("written_total", "Log entries written to MongoDB.",
+			func(s SinkStatsSnapshot) float64 { return float64(s.Written) }),
+		sinkMetric("spilled_total", "Log entries MongoDB rejected that were appended to the fallback file.",
+			func(s SinkStatsSnapshot) float64 { return float64(s.Spilled) }),
+		sinkMetric("dropped_total", "Log entries lost because the queue was full or they could not be stored anywhere.",
+			func(s SinkStatsSnapshot) float64 { return float64(s.Dropped) }),
+		sinkMetric("failed_batches_total", "Batches MongoDB failed to insert.",
+			func(s SinkStatsSnapshot) float64 { return float64(s.FailedBatches) }),
+	)
+}
+
+// MongoDBCore encodes log entries with all their fields and hands them to a MongoDBSink, so
+// logging never waits for MongoDB.
+type MongoDBCore struct {
+	zapcore.LevelEnabler
+	sink   *MongoDBSink
+	fields []zapcore.Field
+}
+
+// NewMongoDBCore writes the entries enabled by level, which can be a zap.AtomicLevel to change
+// it at runtime.
+func NewMongoDBCore(sink *MongoDBSink, level zapcore.LevelEnabler) zapcore.Core {
+	return &MongoDBCore{LevelEnabler: level, sink: sink}
+}
+
+func (c *MongoDBCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(clone.fields[:len(clone.fields):len(clone.fields)], fields...)
+	return &clone
+}
+
+func (c *MongoDBCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+// Write queues the entry. It fails only when a field cannot be stored in BSON.
+func (c *MongoDBCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(encoder)
+	}
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	document := bson.M{}
+	for key, value := range encoder.Fields {
+		document[key] = value
+	}
+
+	// the entry keys win over fields of the same name
+	document["level"] = entry.Level.String()
+	document["message"] = entry.Message
+	document["timestamp"] = entry.Time
+	document["caller"] = entry.Caller.TrimmedPath()
+	document["stack"] = entry.Stack
+	if entry.LoggerName != "" {
+		document["logger"] = entry.LoggerName
+	}
+
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to encode log entry: %w", err)
+	}
+
+	c.sink.enqueue(raw)
+	return nil
+}
+
+// Sync waits until the entries queued so far are written, at most for the write timeout.
+func (c *MongoDBCore) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.sink.cfg.WriteTimeout)
+	defer cancel()
+
+	return c.sink.Flush(ctx)
+}
+
+// MongoDBSink inserts log entries into a collection in batches from a single goroutine.
+type MongoDBSink struct {
+	collection *mongo.Collection
+	cfg        config.MongoSinkConfig
+	queue      chan bson.Raw
+	flushes    chan chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	closed     atomic.Bool
+	fallback   *os.File
+	stats      *SinkStats
+}
+
+// NewMongoDBSink starts writing to the collection; Close stops it.
+func NewMongoDBSink(collection *mongo.Collection, cfg config.MongoSinkConfig) *MongoDBSink {
+	s := &MongoDBSink{
+		collection: collection,
+		cfg:        cfg,
+		queue:      make(chan bson.Raw, cfg.QueueSize),
+		flushes:    make(chan chan struct{}),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+		stats:      &SinkStats{},
+	}
+	sinkVars.Set("mongo", expvar.Func(func() interface{} { return s.Stats() }))
+	currentSink.Store(s)
+
+	go s.run()
+	return s
+}
+
+// Flush waits until the entries queued before the call are written or spilled.
+func (s *MongoDBSink) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+
+	select {
+	case s.flushes <- ack:
+	case <-s.done:
+		return ErrSinkClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes the queued entries and stops the sink. Entries logged afterwards are dropped.
+func (s *MongoDBSink) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		close(s.stop)
+	})
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the counters of the sink and the current queue depth.
+func (s *MongoDBSink) Stats() SinkStatsSnapshot {
+	snapshot := s.stats.snapshot()
+	snapshot.Queued = len(s.queue)
+	snapshot.Capacity = cap(s.queue)
+
+	return snapshot
+}
+
+// enqueue never blocks: when MongoDB falls behind and the queue fills up, entries are dropped.
+func (s *MongoDBSink) enqueue(document bson.Raw) {
+	if s.closed.Load() {
+		s.stats.dropped.Add(1)
+		return
+	}
+
+	select {
+	case s.queue <- document:
+	default:
+		s.stats.dropped.Add(1)
+	}
+}
+
+func (s *MongoDBSink) run() {
+	defer close(s.done)
+	defer s.closeFallback()
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]interface{}, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case document := <-s.queue:
+			batch = append(batch, document)
+			if len(batch) >= s.cfg.BatchSize {
+				batch = s.write(batch)
+			}
+		case <-ticker.C:
+			batch = s.write(batch)
+		case ack := <-s.flushes:
+			batch = s.drain(batch)
+			close(ack)
+		case <-s.stop:
+			s.drain(batch)
+			return
+		}
+	}
+}
+
+// drain writes the batch and everything queued.
+func (s *MongoDBSink) drain(batch []interface{}) []interface{} {
+	for {
+		select {
+		case document := <-s.queue:
+			batch = append(batch, document)
+			if len(batch) >= s.cfg.BatchSize {
+				batch = s.write(batch)
+			}
+		default:
+			return s.write(batch)
+		}
+	}
+}
+
+// write inserts the batch and returns it emptied for reuse.
+func (s *MongoDBSink) write(batch []interface{}) []interface{} {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.WriteTimeout)
+	defer cancel()
+
+	_, err := s.collection.InsertMany(ctx, batch, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		s.stats.written.Add(int64(len(batch)))
+		return batch[:0]
+	}
+
+	s.stats.failedBatches.Add(1)
+	s.stats.setLastError(err)
+
+	// an unordered insert reports the documents it could not write, the others are stored
+	failed := batch
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil && len(bulkErr.WriteErrors) > 0 {
+		failed = make([]interface{}, 0, len(bulkErr.WriteErrors))
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed = append(failed, batch[writeErr.Index])
+		}
+		s.stats.written.Add(int64(len(batch) - len(failed)))
+	}
+
+	s.spill(failed)
+	return batch[:0]
+}
+
+// spill appends the documents to the fallback file as relaxed extended JSON, one per line,
+// which mongoimport can load once MongoDB is back.
+func (s *MongoDBSink) spill(documents []interface{}) {
+	if s.cfg.FallbackFile == "" {
+		s.stats.dropped.Add(int64(len(documents)))
+		return
+	}
+
+	if err := s.openFallback(); err != nil {
+		s.stats.setLastError(err)
+		s.stats.dropped.Add(int64(len(documents)))
+		return
+	}
+
+	for _, document := range documents {
+		line, err := bson.MarshalExtJSON(document, false, false)
+		if err == nil {
+			_, err = s.fallback.Write(append(line, '\n'))
+		}
+
+		if err != nil {
+			s.stats.setLastError(err)
+			s.stats.dropped.Add(1)
+			continue
+		}
+
+		s.stats.spilled.Add(1)
+	}
+}
+
+func (s *MongoDBSink) openFallback() error {
+	if s.fallback != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.cfg.FallbackFile), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.cfg.FallbackFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	s.fallback = file
+	return nil
+}
+
+func (s *MongoDBSink) closeFallback() {
+	if s.fallback != nil {
+		if err := s.fallback.Close(); err != nil {
+			s.stats.setLastError(err)
+		}
+		s.fallback = nil
+	}
+}
+
+// SinkStats counts what happened to the entries given to the sink. Dropped entries are lost:
+// they did not fit into the queue, arrived after Close, or failed to be written and spilled.
+type SinkStats struct {
+	written       atomic.Int64
+	spilled       atomic.Int64
+	dropped       atomic.Int64
+	failedBatches atomic.Int64
+	lastError     atomic.Pointer[string]
+}
+
+type SinkStatsSnapshot struct {
+	Queued        int    `json:"queued"`
+	Capacity      int    `json:"capacity"`
+	Written       int64  `json:"written"`
+	Spilled       int64  `json:"spilled"`
+	Dropped       int64  `json:"dropped"`
+	FailedBatches int64  `json:"failed_batches"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+func (s *SinkStats) snapshot() SinkStatsSnapshot {
+	snapshot := SinkStatsSnapshot{
+		Written:       s.written.Load(),
+		Spilled:       s.spilled.Load(),
+		Dropped:       s.dropped.Load(),
+		FailedBatches: s.failedBatches.Load(),
+	}
+	if lastError := s.lastError.Load(); lastError != nil {
+		snapshot.LastError = *lastError
+	}
+
+	return snapshot
+}
+
+func (s *SinkStats) setLastError(err error) {
+	message := err.Error()
+	s.lastError.Store(&message)
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// unreachableCollection belongs to a client that never finds a server, so every insert fails.
+func unreachableCollection(t *testing.T) *mongo.Collection {
+	t.Helper()
+
+	client, err := mongo.Connect(context.Background(),
+		options.Client().ApplyURI("mongodb://127.0.0.1:1").SetServerSelectionTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	return client.Database("logs").Collection("logs")
+}
+
+func sinkConfig(fallback string) config.MongoSinkConfig {
+	return config.MongoSinkConfig{
+		QueueSize:     100,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		WriteTimeout:  time.Second,
+		FallbackFile:  fallback,
+	}
+}
+
+func TestMongoDBSinkSpillsWithFields(t *testing.T) {
+	fallback := filepath.Join(t.TempDir(), "logs", "fallback.jsonl")
+	sink := NewMongoDBSink(unreachableCollection(t), sinkConfig(fallback))
+	logger := zap.New(NewMongoDBCore(sink, zap.DebugLevel)).With(zap.String("request_id", "abc"))
+
+	logger.Info("list created", zap.Int("list_id", 7), zap.Error(errors.New("boom")))
+	logger.Debug("cache miss", zap.Duration("took", time.Millisecond))
+
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	file, err := os.Open(fallback)
+	if err != nil {
+		t.Fatalf("open fallback: %v", err)
+	}
+	defer file.Close()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("%d entries spilled", len(entries))
+	}
+	if e := entries[0]; e["message"] != "list created" || e["request_id"] != "abc" || e["list_id"] == nil || e["error"] != "boom" {
+		t.Fatalf("entry %+v", e)
+	}
+
+	stats := sink.Stats()
+	if stats.Spilled != 2 || stats.Written != 0 || stats.FailedBatches != 1 || stats.LastError == "" {
+		t.Fatalf("stats %+v", stats)
+	}
+}
+
+func TestMongoDBSinkDropsWhenFull(t *testing.T) {
+	cfg := sinkConfig("")
+	cfg.QueueSize, cfg.BatchSize = 1, 1
+	sink := NewMongoDBSink(unreachableCollection(t), cfg)
+	logger := zap.New(NewMongoDBCore(sink, zap.InfoLevel))
+
+	for i := 0; i < 50; i++ {
+		logger.Info("flood")
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	logger.Info("after close")
+
+	stats := sink.Stats()
+	if stats.Dropped != 51 || stats.Spilled != 0 {
+		t.Fatalf("stats %+v", stats)
+	}
+	if err := logger.Sync(); !errors.Is(err, ErrSinkClosed) {
+		t.Fatalf("sync after close: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Package metrics holds the Prometheus registry served on /metrics. Packages declare their
+// metrics once, at package level, with Factory; collectors reading state that exists from init
+// are added with MustRegister, and those reading state created at startup with Register, which
+// reports a collector registered twice instead of panicking.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+// Namespace prefixes the names of the application metrics, e.g. todo_http_requests_total.
+const Namespace = "todo"
+
+var registry = prometheus.NewRegistry()
+
+// Factory creates metrics registered with the registry.
+var Factory = promauto.With(registry)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// MustRegister adds collectors to the registry. It panics when one of them is registered
+// already, so it is meant for startup.
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+// Register adds collectors to the registry. When one of them fails, the ones added before it
+// are removed again and the error is returned.
+func Register(cs ...prometheus.Collector) error {
+	for i, c := range cs {
+		if err := registry.Register(c); err != nil {
+			Unregister(cs[:i]...)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Unregister removes collectors added with Register, so a restarted component can add them again.
+func Unregister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		registry.Unregister(c)
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
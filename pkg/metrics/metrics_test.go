@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	first := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: Namespace, Name: "test_first"})
+	second := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: Namespace, Name: "test_second"})
+
+	if err := Register(first, second); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if err := Register(prometheus.NewGauge(prometheus.GaugeOpts{Namespace: Namespace, Name: "test_third"}), second); err == nil {
+		t.Fatal("second registration of a collector succeeded")
+	}
+	// the collector registered before the failing one is removed again
+	if err := Register(prometheus.NewGauge(prometheus.GaugeOpts{Namespace: Namespace, Name: "test_third"})); err != nil {
+		t.Fatalf("Register after a failed registration: %v", err)
+	}
+
+	Unregister(first, second)
+	if err := Register(first, second); err != nil {
+		t.Fatalf("Register after Unregister: %v", err)
+	}
+}
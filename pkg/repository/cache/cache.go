@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// schemaVersion is part of every key. Bump it when a cached type changes shape, so values
+// written by older deployments are never decoded into the new type.
+const (
+	namespace     = "todo"
+	schemaVersion = 1
+)
+
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache stores values of a single type. Get returns ErrCacheMiss when the key is absent.
+type Cache[T any] interface {
+	Get(ctx context.Context, key string) (T, error)
+	Set(ctx context.Context, key string, value T) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Keyspace builds the keys of one kind of entity, e.g. todo:v1:list:5.
+type Keyspace struct {
+	prefix string
+}
+
+func NewKeyspace(entity string) Keyspace {
+	return Keyspace{prefix: fmt.Sprintf("%s:v%d:%s", namespace, schemaVersion, entity)}
+}
+
+func (k Keyspace) Key(ids ...int) string {
+	parts := make([]string, 0, len(ids)+1)
+	parts = append(parts, k.prefix)
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(id))
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// Pattern matches every key written by the service, of any schema version.
+const Pattern = namespace + ":*"
+
+var (
+	Lists     = NewKeyspace("list")
+	Items     = NewKeyspace("item")
+	ListItems = NewKeyspace("list-items")
+	UserLists = NewKeyspace("user-lists")
+)
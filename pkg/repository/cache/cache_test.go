@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"testing"
+	"time"
+)
+
+// sameItem compares items with time.Equal, since codecs do not keep the location of times.
+func sameItem(a, b todo.TodoItem) bool {
+	sameTime := func(a, b *time.Time) bool {
+		return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+	}
+
+	return a.Id == b.Id && a.Title == b.Title && a.Description == b.Description && a.Done == b.Done &&
+		a.Priority == b.Priority && a.CreatedAt.Equal(b.CreatedAt) && sameTime(a.DueAt, b.DueAt) && sameTime(a.CompletedAt, b.CompletedAt)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	due := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	item := todo.TodoItem{
+		Id:          5,
+		Title:       "milk",
+		Description: "2 l, ё",
+		Priority:    2,
+		DueAt:       &due,
+		CreatedAt:   time.Date(2024, 5, 1, 12, 30, 0, 123000, time.UTC),
+	}
+	page := todo.ItemsPage{Data: []todo.TodoItem{item, {Id: 6, Title: "bread", Done: true}}, NextCursor: "abc", Total: 7}
+
+	for _, name := range []string{"json", "msgpack"} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := NewCodec(name)
+			if err != nil {
+				t.Fatalf("NewCodec: %v", err)
+			}
+			ctx := context.Background()
+			ttl := NewTTL(time.Minute)
+
+			items := NewMemoryCache[todo.TodoItem](codec, ttl)
+			if err := items.Set(ctx, Items.Key(item.Id), item); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			got, err := items.Get(ctx, Items.Key(item.Id))
+			if err != nil || !sameItem(got, item) {
+				t.Fatalf("got %+v, %v", got, err)
+			}
+
+			pages := NewMemoryCollectionCache[todo.ItemsPage](codec, ttl)
+			if err := pages.SetPage(ctx, ListItems.Key(1), "first", page); err != nil {
+				t.Fatalf("SetPage: %v", err)
+			}
+			gotPage, err := pages.GetPage(ctx, ListItems.Key(1), "first")
+			if err != nil || len(gotPage.Data) != 2 || !sameItem(gotPage.Data[0], page.Data[0]) || !sameItem(gotPage.Data[1], page.Data[1]) ||
+				gotPage.NextCursor != page.NextCursor || gotPage.Total != page.Total {
+				t.Fatalf("got %+v, %v", gotPage, err)
+			}
+		})
+	}
+
+	if _, err := NewCodec("gob"); err == nil {
+		t.Fatal("unknown codec accepted")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	for space, want := range map[Keyspace]string{
+		Lists:     "todo:v1:list:5",
+		Items:     "todo:v1:item:5",
+		ListItems: "todo:v1:list-items:5",
+		UserLists: "todo:v1:user-lists:5",
+	} {
+		if got := space.Key(5); got != want {
+			t.Errorf("key %q, want %q", got, want)
+		}
+	}
+}
+
+func TestListAndItemKeysDoNotCollide(t *testing.T) {
+	ctx := context.Background()
+	// one store for both kinds, like the Redis database the caches share
+	store := NewMemoryCache[string](JSONCodec{}, NewTTL(time.Minute))
+
+	if err := store.Set(ctx, Lists.Key(5), "list 5"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Set(ctx, Items.Key(5), "item 5"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	if got, err := store.Get(ctx, Lists.Key(5)); err != nil || got != "list 5" {
+		t.Fatalf("list 5: %q, %v", got, err)
+	}
+
+	if err := store.Delete(ctx, Items.Key(5)); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, err := store.Get(ctx, Lists.Key(5)); err != nil || got != "list 5" {
+		t.Fatalf("list 5 after deleting item 5: %q, %v", got, err)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	ttl := NewTTL(time.Minute)
+	entities := NewMemoryCache[string](JSONCodec{}, ttl)
+	pages := NewMemoryCollectionCache[string](JSONCodec{}, ttl)
+
+	if _, err := entities.Get(ctx, Lists.Key(1)); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("empty cache: %v", err)
+	}
+
+	ttl.Set(-time.Second)
+	entities.Set(ctx, Lists.Key(1), "expired")
+	pages.SetPage(ctx, UserLists.Key(1), "first", "expired")
+
+	if _, err := entities.Get(ctx, Lists.Key(1)); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expired entity: %v", err)
+	}
+	if _, err := pages.GetPage(ctx, UserLists.Key(1), "first"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expired page: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"sync"
+	"time"
+)
+
+// CollectionCache stores the pages of a collection under a single key, one field per page,
+// so one Delete drops every cached page of the collection however it was sorted or filtered.
+type CollectionCache[T any] interface {
+	GetPage(ctx context.Context, key, page string) (T, error)
+	SetPage(ctx context.Context, key, page string, value T) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// RedisCollectionCache keeps a collection in a Redis hash. The TTL of the hash is renewed
+// on every write, so it expires when none of its pages were loaded for a whole TTL.
+type RedisCollectionCache[T any] struct {
+	client *redis.Client
+	codec  Codec
+	ttl    *TTL
+}
+
+func NewRedisCollectionCache[T any](client *redis.Client, codec Codec, ttl *TTL) *RedisCollectionCache[T] {
+	return &RedisCollectionCache[T]{
+		client: client,
+		codec:  codec,
+		ttl:    ttl,
+	}
+}
+
+func (r *RedisCollectionCache[T]) GetPage(ctx context.Context, key, page string) (T, error) {
+	var value T
+
+	data, err := r.client.HGet(ctx, key, page).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return value, ErrCacheMiss
+	} else if err != nil {
+		return value, err
+	}
+
+	err = r.codec.Unmarshal(data, &value)
+
+	return value, err
+}
+
+func (r *RedisCollectionCache[T]) SetPage(ctx context.Context, key, page string, value T) error {
+	data, err := r.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, page, data)
+		pipe.Expire(ctx, key, r.ttl.Get())
+		return nil
+	})
+
+	return err
+}
+
+func (r *RedisCollectionCache[T]) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return r.client.Del(ctx, keys...).Err()
+}
+
+type memoryCollection struct {
+	pages     map[string][]byte
+	expiresAt time.Time
+}
+
+// MemoryCollectionCache is the in-process counterpart of RedisCollectionCache.
+type MemoryCollectionCache[T any] struct {
+	mu          sync.Mutex
+	collections map[string]*memoryCollection
+	codec       Codec
+	ttl         *TTL
+}
+
+func NewMemoryCollectionCache[T any](codec Codec, ttl *TTL) *MemoryCollectionCache[T] {
+	return &MemoryCollectionCache[T]{
+		collections: make(map[string]*memoryCollection),
+		codec:       codec,
+		ttl:         ttl,
+	}
+}
+
+func (m *MemoryCollectionCache[T]) GetPage(_ context.Context, key, page string) (T, error) {
+	var value T
+
+	m.mu.Lock()
+	var data []byte
+	collection, ok := m.collections[key]
+	if ok && time.Now().After(collection.expiresAt) {
+		delete(m.collections, key)
+		ok = false
+	}
+	if ok {
+		data, ok = collection.pages[page]
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return value, ErrCacheMiss
+	}
+
+	err := m.codec.Unmarshal(data, &value)
+
+	return value, err
+}
+
+func (m *MemoryCollectionCache[T]) SetPage(_ context.Context, key, page string, value T) error {
+	data, err := m.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	collection, ok := m.collections[key]
+	if !ok || time.Now().After(collection.expiresAt) {
+		collection = &memoryCollection{pages: make(map[string][]byte)}
+		m.collections[key] = collection
+	}
+	collection.pages[page] = data
+	collection.expiresAt = time.Now().Add(m.ttl.Get())
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *MemoryCollectionCache[T]) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	for _, key := range keys {
+		delete(m.collections, key)
+	}
+	m.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Deleter is the part of a cache the Invalidator needs.
+type Deleter interface {
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Event is a change of stored lists or items. Services publish events after the change
+// is committed and the Invalidator evicts every cached entity and collection it made stale.
+type Event interface {
+	stale() []staleKey
+}
+
+type staleKey struct {
+	space Keyspace
+	id    int
+}
+
+type ListCreated struct {
+	UserId int
+}
+
+// ListUpdated is published when the list itself changes; every user it is shared with sees it.
+type ListUpdated struct {
+	ListId  int
+	UserIds []int
+}
+
+// ListDeleted also carries the items deleted together with the list.
+type ListDeleted struct {
+	ListId  int
+	UserIds []int
+	ItemIds []int
+}
+
+// AccessChanged is published when the list is shared with or revoked from the user.
+type AccessChanged struct {
+	ListId int
+	UserId int
+}
+
+type ItemCreated struct {
+	ListId int
+}
+
+// ItemChanged is published when the item is updated or deleted.
+type ItemChanged struct {
+	ListId int
+	ItemId int
+}
+
+func (e ListCreated) stale() []staleKey {
+	return []staleKey{{UserLists, e.UserId}}
+}
+
+func (e ListUpdated) stale() []staleKey {
+	keys := []staleKey{{Lists, e.ListId}}
+	for _, userId := range e.UserIds {
+		keys = append(keys, staleKey{UserLists, userId})
+	}
+
+	return keys
+}
+
+func (e ListDeleted) stale() []staleKey {
+	keys := []staleKey{{Lists, e.ListId}, {ListItems, e.ListId}}
+	for _, userId := range e.UserIds {
+		keys = append(keys, staleKey{UserLists, userId})
+	}
+	for _, itemId := range e.ItemIds {
+		keys = append(keys, staleKey{Items, itemId})
+	}
+
+	return keys
+}
+
+func (e AccessChanged) stale() []staleKey {
+	return []staleKey{{UserLists, e.UserId}}
+}
+
+func (e ItemCreated) stale() []staleKey {
+	return []staleKey{{ListItems, e.ListId}}
+}
+
+func (e ItemChanged) stale() []staleKey {
+	return []staleKey{{Items, e.ItemId}, {ListItems, e.ListId}}
+}
+
+// Invalidator routes the keys made stale by events to the caches holding their keyspaces.
+// Keyspaces without a cache are not cached, so their keys are skipped.
+type Invalidator struct {
+	caches map[Keyspace]Deleter
+}
+
+func NewInvalidator(caches map[Keyspace]Deleter) *Invalidator {
+	return &Invalidator{caches: caches}
+}
+
+func (i *Invalidator) Publish(ctx context.Context, events ...Event) error {
+	keys := make(map[Keyspace][]string)
+	for _, event := range events {
+		for _, k := range event.stale() {
+			keys[k.space] = append(keys[k.space], k.space.Key(k.id))
+		}
+	}
+
+	var firstErr error
+	for space, spaceKeys := range keys {
+		cache, ok := i.caches[space]
+		if !ok {
+			continue
+		}
+
+		if err := cache.Delete(ctx, spaceKeys...); err != nil {
+			// the keys stay stale until they expire
+			logging.FromContext(ctx).Warn("cache invalidation failed", zap.Strings("keys", spaceKeys), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
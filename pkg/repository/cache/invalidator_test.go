@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// recorder is a Deleter remembering the keys it was asked to delete.
+type recorder struct {
+	keys []string
+	err  error
+}
+
+func (r *recorder) Delete(_ context.Context, keys ...string) error {
+	r.keys = append(r.keys, keys...)
+	return r.err
+}
+
+func (r *recorder) deleted() string {
+	sort.Strings(r.keys)
+	return strings.Join(r.keys, " ")
+}
+
+func TestInvalidatorRoutesEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		want   map[Keyspace]string
+	}{
+		{
+			name:   "list created",
+			events: []Event{ListCreated{UserId: 1}},
+			want:   map[Keyspace]string{UserLists: "todo:v1:user-lists:1"},
+		},
+		{
+			name:   "list updated",
+			events: []Event{ListUpdated{ListId: 3, UserIds: []int{1, 2}}},
+			want:   map[Keyspace]string{Lists: "todo:v1:list:3", UserLists: "todo:v1:user-lists:1 todo:v1:user-lists:2"},
+		},
+		{
+			name:   "list deleted with its items",
+			events: []Event{ListDeleted{ListId: 3, UserIds: []int{1, 2}, ItemIds: []int{7, 8}}},
+			want: map[Keyspace]string{
+				Lists:     "todo:v1:list:3",
+				ListItems: "todo:v1:list-items:3",
+				UserLists: "todo:v1:user-lists:1 todo:v1:user-lists:2",
+				Items:     "todo:v1:item:7 todo:v1:item:8",
+			},
+		},
+		{
+			name:   "access changed",
+			events: []Event{AccessChanged{ListId: 3, UserId: 2}},
+			want:   map[Keyspace]string{UserLists: "todo:v1:user-lists:2"},
+		},
+		{
+			name:   "item created",
+			events: []Event{ItemCreated{ListId: 3}},
+			want:   map[Keyspace]string{ListItems: "todo:v1:list-items:3"},
+		},
+		{
+			name:   "item changed",
+			events: []Event{ItemChanged{ListId: 3, ItemId: 7}},
+			want:   map[Keyspace]string{Items: "todo:v1:item:7", ListItems: "todo:v1:list-items:3"},
+		},
+		{
+			name:   "several events",
+			events: []Event{ItemChanged{ListId: 3, ItemId: 7}, ItemChanged{ListId: 4, ItemId: 9}},
+			want:   map[Keyspace]string{Items: "todo:v1:item:7 todo:v1:item:9", ListItems: "todo:v1:list-items:3 todo:v1:list-items:4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caches := map[Keyspace]*recorder{Lists: {}, Items: {}, ListItems: {}, UserLists: {}}
+			deleters := make(map[Keyspace]Deleter)
+			for space, c := range caches {
+				deleters[space] = c
+			}
+
+			if err := NewInvalidator(deleters).Publish(context.Background(), tt.events...); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+
+			for space, c := range caches {
+				if got := c.deleted(); got != tt.want[space] {
+					t.Errorf("%s: deleted %q, want %q", space.prefix, got, tt.want[space])
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidatorSkipsUncachedAndReportsFailures(t *testing.T) {
+	failed := errors.New("redis is down")
+	lists := &recorder{err: failed}
+	pages := &recorder{}
+
+	// items and their pages are not cached
+	err := NewInvalidator(map[Keyspace]Deleter{Lists: lists, UserLists: pages}).
+		Publish(context.Background(), ListDeleted{ListId: 3, UserIds: []int{1}, ItemIds: []int{7}})
+	if !errors.Is(err, failed) {
+		t.Fatalf("error %v", err)
+	}
+
+	// a failing cache does not keep the others stale
+	if lists.deleted() != "todo:v1:list:3" || pages.deleted() != "todo:v1:user-lists:1" {
+		t.Fatalf("deleted %q and %q", lists.deleted(), pages.deleted())
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"hash/fnv"
+	"sync/atomic"
+)
+
+// generationStripes is the number of invalidation counters a Loader spreads its collections over.
+const generationStripes = 256
+
+// Loader reads collection pages through the cache. Concurrent misses of the same page are
+// collapsed into one load, so an invalidation of a busy collection sends a single query
+// to Postgres instead of one per waiting reader.
+//
+// Every Delete bumps the generation of the collections it evicts. A load that saw the
+// generation change may have read the collection before the change, so its page is not
+// cached. Collections share a fixed number of generations, which bounds the memory they take
+// at the price of skipping a write now and then for an unrelated invalidation.
+type Loader[T any] struct {
+	cache       CollectionCache[T]
+	stats       *Stats
+	group       singleflight.Group
+	generations [generationStripes]atomic.Uint64
+}
+
+func NewLoader[T any](cache CollectionCache[T], stats *Stats) *Loader[T] {
+	return &Loader[T]{
+		cache: cache,
+		stats: stats,
+	}
+}
+
+func (l *Loader[T]) Load(ctx context.Context, key, page string, load func(ctx context.Context) (T, error)) (T, error) {
+	value, err := l.cache.GetPage(ctx, key, page)
+	l.stats.record(err)
+	if err == nil {
+		return value, nil
+	} else if !errors.Is(err, ErrCacheMiss) {
+		logging.FromContext(ctx).Warn("cache read failed", zap.String("key", key), zap.String("page", page), zap.Error(err))
+	}
+
+	v, err, _ := l.group.Do(key+"#"+page, func() (interface{}, error) {
+		// the load is shared, so it must not fail because the caller that started it went away
+		ctx := context.WithoutCancel(ctx)
+		generation := l.generation(key)
+		started := generation.Load()
+
+		value, err := load(ctx)
+		if err != nil {
+			return value, err
+		}
+
+		if generation.Load() != started {
+			return value, nil
+		}
+
+		if err = l.cache.SetPage(ctx, key, page, value); err != nil {
+			l.stats.errors.Add(1)
+			logging.FromContext(ctx).Warn("cache write failed", zap.String("key", key), zap.String("page", page), zap.Error(err))
+		} else if generation.Load() != started {
+			// a Delete ran between the check and the write and may have missed the page
+			if err = l.cache.Delete(ctx, key); err != nil {
+				logging.FromContext(ctx).Warn("cache invalidation failed", zap.String("key", key), zap.Error(err))
+			}
+		}
+
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return v.(T), nil
+}
+
+// Delete evicts whole collections. The generations are bumped first, so a load finishing
+// after the eviction either sees the bump or writes before the eviction.
+func (l *Loader[T]) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		l.generation(key).Add(1)
+	}
+
+	return l.cache.Delete(ctx, keys...)
+}
+
+func (l *Loader[T]) generation(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return &l.generations[h.Sum32()%generationStripes]
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestLoader() *Loader[string] {
+	return NewLoader[string](NewMemoryCollectionCache[string](JSONCodec{}, NewTTL(time.Minute)), NewStats("test_pages"))
+}
+
+func TestLoaderCachesPages(t *testing.T) {
+	ctx := context.Background()
+	loader := newTestLoader()
+
+	var loads int
+	load := func(ctx context.Context) (string, error) {
+		loads++
+		return "page", nil
+	}
+
+	for range 2 {
+		if v, err := loader.Load(ctx, UserLists.Key(1), "first", load); err != nil || v != "page" {
+			t.Fatalf("got %q, %v", v, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("loaded %d times", loads)
+	}
+
+	if err := loader.Delete(ctx, UserLists.Key(1)); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	loader.Load(ctx, UserLists.Key(1), "first", load)
+	if loads != 2 {
+		t.Fatalf("loaded %d times after Delete", loads)
+	}
+}
+
+func TestLoaderDoesNotCacheFailures(t *testing.T) {
+	ctx := context.Background()
+	loader := newTestLoader()
+	failed := errors.New("postgres is down")
+
+	if _, err := loader.Load(ctx, UserLists.Key(1), "first", func(ctx context.Context) (string, error) {
+		return "", failed
+	}); !errors.Is(err, failed) {
+		t.Fatalf("error %v", err)
+	}
+
+	if v, err := loader.Load(ctx, UserLists.Key(1), "first", func(ctx context.Context) (string, error) {
+		return "page", nil
+	}); err != nil || v != "page" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+}
+
+func TestLoaderCollapsesConcurrentLoads(t *testing.T) {
+	const readers = 10
+	ctx := context.Background()
+	loader := newTestLoader()
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		loads.Add(1)
+		<-release
+		return "page", nil
+	}
+
+	var wg sync.WaitGroup
+	values := make([]string, readers)
+	for i := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[i], _ = loader.Load(ctx, UserLists.Key(1), "first", load)
+		}()
+	}
+
+	// every reader missed the cache, give the last of them the time to join the load
+	for loader.stats.Snapshot().Misses < readers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loaded %d times", n)
+	}
+	for i, v := range values {
+		if v != "page" {
+			t.Fatalf("reader %d got %q", i, v)
+		}
+	}
+}
+
+func TestLoaderSkipsPagesInvalidatedWhileLoading(t *testing.T) {
+	ctx := context.Background()
+	loader := newTestLoader()
+	key := UserLists.Key(1)
+
+	// the collection changes after the load read it
+	v, err := loader.Load(ctx, key, "first", func(ctx context.Context) (string, error) {
+		loader.Delete(ctx, key)
+		return "stale", nil
+	})
+	if err != nil || v != "stale" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+
+	if _, err := loader.cache.GetPage(ctx, key, "first"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("stale page cached: %v", err)
+	}
+
+	// loads of other collections are cached still
+	loader.Load(ctx, UserLists.Key(2), "first", func(ctx context.Context) (string, error) {
+		return "fresh", nil
+	})
+	if _, err := loader.cache.GetPage(ctx, UserLists.Key(2), "first"); err != nil {
+		t.Fatalf("fresh page not cached: %v", err)
+	}
+}
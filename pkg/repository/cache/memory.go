@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemoryCache is an in-process Cache for tests and single-instance setups. Values are
+// stored encoded, like in Redis, so callers never share memory with the cache.
+type MemoryCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	codec   Codec
+	ttl     *TTL
+}
+
+func NewMemoryCache[T any](codec Codec, ttl *TTL) *MemoryCache[T] {
+	return &MemoryCache[T]{
+		entries: make(map[string]memoryEntry),
+		codec:   codec,
+		ttl:     ttl,
+	}
+}
+
+func (m *MemoryCache[T]) Get(_ context.Context, key string) (T, error) {
+	var value T
+
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if ok && time.Now().After(entry.expiresAt) {
+		delete(m.entries, key)
+		ok = false
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return value, ErrCacheMiss
+	}
+
+	err := m.codec.Unmarshal(entry.data, &value)
+
+	return value, err
+}
+
+func (m *MemoryCache[T]) Set(_ context.Context, key string, value T) error {
+	data, err := m.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.entries[key] = memoryEntry{data: data, expiresAt: time.Now().Add(m.ttl.Get())}
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *MemoryCache[T]) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	m.mu.Unlock()
+
+	return nil
+}
@@ -2,80 +2,85 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
-	todo "github.com/dafuqqqyunglean/todoRestAPI"
 	"github.com/go-redis/redis/v8"
-	"time"
 )
 
-type RedisCache struct {
-	client   *redis.Client
-	cacheKey string
-	ttl      time.Duration
+type RedisCache[T any] struct {
+	client *redis.Client
+	codec  Codec
+	ttl    *TTL
 }
 
-func NewRedisCache(client *redis.Client, cacheKey string, ttl time.Duration) RedisCache {
-	return RedisCache{
-		client:   client,
-		cacheKey: cacheKey,
-		ttl:      ttl,
+func NewRedisCache[T any](client *redis.Client, codec Codec, ttl *TTL) *RedisCache[T] {
+	return &RedisCache[T]{
+		client: client,
+		codec:  codec,
+		ttl:    ttl,
 	}
 }
 
-func (r *RedisCache) SetItem(ctx context.Context, userId, itemId int, item todo.TodoItem) {
-	cacheKey := fmt.Sprintf(r.cacheKey, userId, itemId)
+func (r *RedisCache[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
 
-	itemJSON, _ := json.Marshal(item)
-
-	r.client.Set(ctx, cacheKey, itemJSON, r.ttl)
-}
-
-func (r *RedisCache) GetItem(ctx context.Context, userId, itemId int) (todo.TodoItem, error) {
-	cacheKey := fmt.Sprintf(r.cacheKey, userId, itemId)
-	var item todo.TodoItem
-
-	cachedItem, err := r.client.Get(ctx, cacheKey).Result()
+	data, err := r.client.Get(ctx, key).Bytes()
 	if errors.Is(err, redis.Nil) {
-		return item, fmt.Errorf("item not found in cache")
+		return value, ErrCacheMiss
 	} else if err != nil {
-		return item, err
+		return value, err
 	}
 
-	if err := json.Unmarshal([]byte(cachedItem), &item); err != nil {
-		return item, err
-	}
+	err = r.codec.Unmarshal(data, &value)
 
-	return item, nil
+	return value, err
 }
 
-func (r *RedisCache) SetList(ctx context.Context, userId, itemId int, list todo.TodoList) {
-	cacheKey := fmt.Sprintf(r.cacheKey, userId, itemId)
-
-	itemJSON, _ := json.Marshal(list)
+func (r *RedisCache[T]) Set(ctx context.Context, key string, value T) error {
+	data, err := r.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
 
-	r.client.Set(ctx, cacheKey, itemJSON, r.ttl)
+	return r.client.Set(ctx, key, data, r.ttl.Get()).Err()
 }
 
-func (r *RedisCache) GetList(ctx context.Context, userId, listId int) (todo.TodoList, error) {
-	cacheKey := fmt.Sprintf(r.cacheKey, userId, listId)
-	var list todo.TodoList
-
-	cachedList, err := r.client.Get(ctx, cacheKey).Result()
-	if errors.Is(err, redis.Nil) {
-		return list, fmt.Errorf("item not found in cache")
-	} else if err != nil {
-		return list, err
+func (r *RedisCache[T]) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
 	}
 
-	if err = json.Unmarshal([]byte(cachedList), &list); err == nil {
-		return list, nil
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// Flush deletes every key matching Pattern and returns how many were deleted. It scans
+// instead of using KEYS, so a large cache does not block Redis.
+func Flush(ctx context.Context, client *redis.Client) (int64, error) {
+	var deleted int64
+
+	iter := client.Scan(ctx, 0, Pattern, 500).Iterator()
+	batch := make([]string, 0, 500)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			n, err := client.Unlink(ctx, batch...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
 	}
 
-	return list, err
-}
+	if len(batch) > 0 {
+		n, err := client.Unlink(ctx, batch...).Result()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
 
-func (r *RedisCache) Delete(ctx context.Context, userId, key int) {
-	r.client.Del(ctx, fmt.Sprintf(r.cacheKey, userId, key))
+	return deleted, nil
 }
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"sync/atomic"
+)
+
+// vars is published as "cache" on /admin/debug/vars, with one entry per named Stats.
+var vars = expvar.NewMap("cache")
+
+var reads = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Name:      "cache_reads_total",
+	Help:      "Cache reads by cache and result: hit, miss or error.",
+}, []string{"cache", "result"})
+
+// Stats counts the outcomes of cache reads. Errors are reads that failed for another
+// reason than a miss, e.g. Redis being unreachable; they are served from Postgres too.
+type Stats struct {
+	name   string
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+// NewStats creates counters published under the given name, replacing earlier ones of that name.
+func NewStats(name string) *Stats {
+	s := &Stats{name: name}
+	vars.Set(name, expvar.Func(func() interface{} { return s.Snapshot() }))
+
+	return s
+}
+
+type StatsSnapshot struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Errors int64 `json:"errors"`
+}
+
+func (s *Stats) Snapshot() StatsSnapshot {
+	return StatsSnapshot{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+		Errors: s.errors.Load(),
+	}
+}
+
+func (s *Stats) record(err error) {
+	switch {
+	case err == nil:
+		s.hits.Add(1)
+		reads.WithLabelValues(s.name, "hit").Inc()
+	case errors.Is(err, ErrCacheMiss):
+		s.misses.Add(1)
+		reads.WithLabelValues(s.name, "miss").Inc()
+	default:
+		s.errors.Add(1)
+		reads.WithLabelValues(s.name, "error").Inc()
+	}
+}
+
+// InstrumentedCache counts the reads of the wrapped cache.
+type InstrumentedCache[T any] struct {
+	Cache[T]
+	stats *Stats
+}
+
+func NewInstrumentedCache[T any](cache Cache[T], stats *Stats) *InstrumentedCache[T] {
+	return &InstrumentedCache[T]{
+		Cache: cache,
+		stats: stats,
+	}
+}
+
+// Get counts the read. Failures other than misses are logged, since the caller falls back
+// to Postgres and does not report them.
+func (c *InstrumentedCache[T]) Get(ctx context.Context, key string) (T, error) {
+	value, err := c.Cache.Get(ctx, key)
+	c.stats.record(err)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		logging.FromContext(ctx).Warn("cache read failed", zap.String("key", key), zap.Error(err))
+	}
+
+	return value, err
+}
+
+func (c *InstrumentedCache[T]) Set(ctx context.Context, key string, value T) error {
+	err := c.Cache.Set(ctx, key, value)
+	if err != nil {
+		logging.FromContext(ctx).Warn("cache write failed", zap.String("key", key), zap.Error(err))
+	}
+
+	return err
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// brokenCollection fails every call, like a RedisCollectionCache without Redis.
+type brokenCollection struct{}
+
+var errBroken = errors.New("redis is down")
+
+func (brokenCollection) GetPage(context.Context, string, string) (string, error) {
+	return "", errBroken
+}
+
+func (brokenCollection) SetPage(context.Context, string, string, string) error {
+	return errBroken
+}
+
+func (brokenCollection) Delete(context.Context, ...string) error {
+	return errBroken
+}
+
+func TestStatsCountReads(t *testing.T) {
+	ctx := context.Background()
+	stats := NewStats("test_entities")
+	entities := NewInstrumentedCache[string](NewMemoryCache[string](JSONCodec{}, NewTTL(time.Minute)), stats)
+
+	entities.Get(ctx, Lists.Key(1))
+	entities.Set(ctx, Lists.Key(1), "list")
+	entities.Get(ctx, Lists.Key(1))
+	entities.Get(ctx, Lists.Key(1))
+
+	if got, want := stats.Snapshot(), (StatsSnapshot{Hits: 2, Misses: 1}); got != want {
+		t.Fatalf("stats %+v, want %+v", got, want)
+	}
+}
+
+func TestStatsCountLoaderErrors(t *testing.T) {
+	ctx := context.Background()
+	stats := NewStats("test_broken_pages")
+	loader := NewLoader[string](brokenCollection{}, stats)
+
+	// the page is served from the load when the cache fails
+	v, err := loader.Load(ctx, UserLists.Key(1), "first", func(ctx context.Context) (string, error) {
+		return "page", nil
+	})
+	if err != nil || v != "page" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+
+	// the failed read and the failed write
+	if got, want := stats.Snapshot(), (StatsSnapshot{Errors: 2}); got != want {
+		t.Fatalf("stats %+v, want %+v", got, want)
+	}
+}
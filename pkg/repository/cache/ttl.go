@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TTL is the time to live of cached values. It is shared by the caches and can be changed
+// while they are in use; values already cached keep the TTL they were written with.
+type TTL struct {
+	d atomic.Int64
+}
+
+func NewTTL(d time.Duration) *TTL {
+	t := &TTL{}
+	t.Set(d)
+	return t
+}
+
+func (t *TTL) Get() time.Duration {
+	return time.Duration(t.d.Load())
+}
+
+func (t *TTL) Set(d time.Duration) {
+	t.d.Store(int64(d))
+}
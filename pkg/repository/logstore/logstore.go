@@ -0,0 +1,277 @@
+// Package logstore reads and trims the application log written to MongoDB by the logging
+// package.
+package logstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
+)
+
+var ErrInvalidCursor = apperror.NewValidation("invalid_cursor", "invalid cursor")
+
+// ttlIndex is the name of the index MongoDB removes expired entries by.
+const ttlIndex = "timestamp_ttl"
+
+// indexOptionsConflict is reported when an index exists under the name with other options.
+const indexOptionsConflict = 85
+
+type LogRepository interface {
+	EnsureIndexes(ctx context.Context, ttl time.Duration) error
+	Query(ctx context.Context, filter todo.LogFilter) (todo.LogsPage, error)
+	Size(ctx context.Context) (bytes, count int64, err error)
+	DeleteOldest(ctx context.Context, n int64) (int64, error)
+}
+
+type LogMongo struct {
+	collection *mongo.Collection
+}
+
+func NewLogMongo(collection *mongo.Collection) *LogMongo {
+	return &LogMongo{collection: collection}
+}
+
+// EnsureIndexes creates the TTL index and the indexes of the filters, and updates the TTL
+// of an existing TTL index.
+func (r *LogMongo) EnsureIndexes(ctx context.Context, ttl time.Duration) error {
+	expireAfter := int32(ttl / time.Second)
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "timestamp", Value: 1}},
+		Options: options.Index().SetName(ttlIndex).SetExpireAfterSeconds(expireAfter),
+	})
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == indexOptionsConflict {
+		err = r.collection.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: r.collection.Name()},
+			{Key: "index", Value: bson.D{{Key: "name", Value: ttlIndex}, {Key: "expireAfterSeconds", Value: expireAfter}}},
+		}).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "request_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "level", Value: 1}, {Key: "timestamp", Value: -1}}},
+	})
+	return err
+}
+
+// Query returns a page of the entries matching the filter, newest first.
+func (r *LogMongo) Query(ctx context.Context, filter todo.LogFilter) (todo.LogsPage, error) {
+	var page todo.LogsPage
+
+	query, err := buildFilter(filter)
+	if err != nil {
+		return page, err
+	}
+
+	cursor, err := r.collection.Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(filter.Limit+1)))
+	if err != nil {
+		return page, err
+	}
+
+	var documents []bson.M
+	if err = cursor.All(ctx, &documents); err != nil {
+		return page, err
+	}
+
+	page.Data = make([]todo.LogEntry, 0, len(documents))
+	for _, document := range documents {
+		page.Data = append(page.Data, toEntry(document))
+	}
+
+	// one entry more than asked for tells whether there is a next page
+	if len(page.Data) > filter.Limit {
+		page.Data = page.Data[:filter.Limit]
+		last := page.Data[len(page.Data)-1]
+		page.NextCursor = encodeCursor(last.Timestamp, last.Id)
+	}
+
+	return page, nil
+}
+
+// Size returns the uncompressed size of the entries and their number.
+func (r *LogMongo) Size(ctx context.Context) (int64, int64, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$collStats", Value: bson.D{{Key: "storageStats", Value: bson.D{}}}}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var stats []struct {
+		StorageStats struct {
+			Size  int64 `bson:"size"`
+			Count int64 `bson:"count"`
+		} `bson:"storageStats"`
+	}
+	if err = cursor.All(ctx, &stats); err != nil || len(stats) == 0 {
+		return 0, 0, err
+	}
+
+	return stats[0].StorageStats.Size, stats[0].StorageStats.Count, nil
+}
+
+// DeleteOldest deletes the n oldest entries and returns how many were deleted.
+func (r *LogMongo) DeleteOldest(ctx context.Context, n int64) (int64, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+
+	var newest struct {
+		Id        primitive.ObjectID `bson:"_id"`
+		Timestamp time.Time          `bson:"timestamp"`
+	}
+	err := r.collection.FindOne(ctx, bson.D{}, options.FindOne().
+		SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(n-1).
+		SetProjection(bson.D{{Key: "timestamp", Value: 1}})).Decode(&newest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// fewer than n entries, all of them go
+		result, err := r.collection.DeleteMany(ctx, bson.D{})
+		if err != nil {
+			return 0, err
+		}
+		return result.DeletedCount, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "timestamp", Value: bson.D{{Key: "$lt", Value: newest.Timestamp}}}},
+		bson.D{{Key: "timestamp", Value: newest.Timestamp}, {Key: "_id", Value: bson.D{{Key: "$lte", Value: newest.Id}}}},
+	}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func buildFilter(filter todo.LogFilter) (bson.D, error) {
+	query := bson.D{}
+
+	if len(filter.Levels) > 0 {
+		query = append(query, bson.E{Key: "level", Value: bson.D{{Key: "$in", Value: filter.Levels}}})
+	}
+
+	timestamp := bson.D{}
+	if !filter.From.IsZero() {
+		timestamp = append(timestamp, bson.E{Key: "$gte", Value: filter.From})
+	}
+	if !filter.To.IsZero() {
+		timestamp = append(timestamp, bson.E{Key: "$lt", Value: filter.To})
+	}
+	if len(timestamp) > 0 {
+		query = append(query, bson.E{Key: "timestamp", Value: timestamp})
+	}
+
+	if filter.RequestId != "" {
+		query = append(query, bson.E{Key: "request_id", Value: filter.RequestId})
+	}
+	if filter.UserId != 0 {
+		query = append(query, bson.E{Key: "user_id", Value: filter.UserId})
+	}
+	if filter.Caller != "" {
+		query = append(query, bson.E{Key: "caller", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(filter.Caller)}})
+	}
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		query = append(query, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "timestamp", Value: bson.D{{Key: "$lt", Value: c.Timestamp}}}},
+			bson.D{{Key: "timestamp", Value: c.Timestamp}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: c.Id}}}},
+		}})
+	}
+
+	return query, nil
+}
+
+// entryKeys are stored by the logging package for every entry; the other keys are fields.
+var entryKeys = map[string]bool{
+	"_id": true, "timestamp": true, "level": true, "message": true, "caller": true,
+	"logger": true, "stack": true, "request_id": true, "user_id": true,
+}
+
+func toEntry(document bson.M) todo.LogEntry {
+	entry := todo.LogEntry{}
+
+	if id, ok := document["_id"].(primitive.ObjectID); ok {
+		entry.Id = id.Hex()
+	}
+	if timestamp, ok := document["timestamp"].(primitive.DateTime); ok {
+		entry.Timestamp = timestamp.Time().UTC()
+	}
+	entry.Level, _ = document["level"].(string)
+	entry.Message, _ = document["message"].(string)
+	entry.Caller, _ = document["caller"].(string)
+	entry.Logger, _ = document["logger"].(string)
+	entry.Stack, _ = document["stack"].(string)
+	entry.RequestId, _ = document["request_id"].(string)
+
+	switch userId := document["user_id"].(type) {
+	case int32:
+		entry.UserId = int(userId)
+	case int64:
+		entry.UserId = int(userId)
+	}
+
+	for key, value := range document {
+		if entryKeys[key] {
+			continue
+		}
+		if entry.Fields == nil {
+			entry.Fields = make(map[string]interface{})
+		}
+		entry.Fields[key] = value
+	}
+
+	return entry
+}
+
+// cursor points at the last entry of a page; the id breaks ties between equal timestamps.
+type cursor struct {
+	Timestamp time.Time          `json:"t"`
+	Id        primitive.ObjectID `json:"id"`
+}
+
+func encodeCursor(timestamp time.Time, id string) string {
+	objectId, _ := primitive.ObjectIDFromHex(id)
+
+	b, _ := json.Marshal(cursor{Timestamp: timestamp, Id: objectId})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(b, &c); err != nil || c.Id.IsZero() {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
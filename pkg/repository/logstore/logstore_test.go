@@ -0,0 +1,79 @@
+package logstore
+
+import (
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildFilter(t *testing.T) {
+	from := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	id := primitive.NewObjectID()
+	last := from.Add(time.Minute)
+
+	query, err := buildFilter(todo.LogFilter{
+		Levels:    []string{"warn", "error"},
+		From:      from,
+		RequestId: "abc",
+		UserId:    7,
+		Caller:    "list/list.go",
+		Cursor:    encodeCursor(last, id.Hex()),
+	})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	want := bson.D{
+		{Key: "level", Value: bson.D{{Key: "$in", Value: []string{"warn", "error"}}}},
+		{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: from}}},
+		{Key: "request_id", Value: "abc"},
+		{Key: "user_id", Value: 7},
+		{Key: "caller", Value: primitive.Regex{Pattern: `^list/list\.go`}},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "timestamp", Value: bson.D{{Key: "$lt", Value: last}}}},
+			bson.D{{Key: "timestamp", Value: last}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: id}}}},
+		}},
+	}
+	if !reflect.DeepEqual(query, want) {
+		t.Fatalf("query\n%v\nwant\n%v", query, want)
+	}
+
+	if _, err = buildFilter(todo.LogFilter{Cursor: "garbage"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("garbage cursor: %v", err)
+	}
+}
+
+func TestToEntry(t *testing.T) {
+	id := primitive.NewObjectID()
+	timestamp := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	entry := toEntry(bson.M{
+		"_id":        id,
+		"timestamp":  primitive.NewDateTimeFromTime(timestamp),
+		"level":      "error",
+		"message":    "request failed",
+		"caller":     "utility/errorResponse.go:108",
+		"stack":      "",
+		"request_id": "abc",
+		"user_id":    int64(7),
+		"status":     int64(500),
+	})
+
+	want := todo.LogEntry{
+		Id:        id.Hex(),
+		Timestamp: timestamp,
+		Level:     "error",
+		Message:   "request failed",
+		Caller:    "utility/errorResponse.go:108",
+		RequestId: "abc",
+		UserId:    7,
+		Fields:    map[string]interface{}{"status": int64(500)},
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Fatalf("entry %+v", entry)
+	}
+}
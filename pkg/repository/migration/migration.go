@@ -0,0 +1,152 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/schema"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jmoiron/sqlx"
+	"io/fs"
+)
+
+var (
+	ErrSchemaBehind = errors.New("database schema is behind the application")
+	ErrSchemaDirty  = errors.New("a migration failed halfway and must be fixed by hand")
+)
+
+// Status describes the schema of the database. Version is 0 for a database never migrated.
+type Status struct {
+	Version uint
+	Latest  uint
+	Dirty   bool
+}
+
+func (s Status) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// Migrator applies the migrations embedded from schema/. It keeps the schema_migrations
+// table of the migrate CLI, so databases migrated by hand carry on from their version.
+type Migrator struct {
+	migrate *migrate.Migrate
+	latest  uint
+}
+
+// NewMigrator holds one connection of the pool until Close. Up and Down take a Postgres
+// advisory lock on it, so application instances starting together migrate one at a time.
+func NewMigrator(ctx context.Context, db *sqlx.DB) (*Migrator, error) {
+	src, err := iofs.New(schema.Migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := latestVersion(src)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := postgres.WithConnection(ctx, conn.Conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Migrator{
+		migrate: m,
+		latest:  latest,
+	}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up() error {
+	if err := m.migrate.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate up: %w", err)
+	}
+
+	return nil
+}
+
+// Down rolls back the given number of the latest migrations.
+func (m *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("migrate down: steps must be positive, got %d", steps)
+	}
+
+	if err := m.migrate.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate down: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) Status() (Status, error) {
+	version, dirty, err := m.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return Status{Latest: m.latest}, nil
+	} else if err != nil {
+		return Status{}, err
+	}
+
+	return Status{Version: version, Latest: m.latest, Dirty: dirty}, nil
+}
+
+// Check refuses a schema the application cannot run against: one with pending migrations
+// or one left dirty by a failed migration. A schema ahead of the binary is accepted, so
+// an older release can keep serving while a newer one rolls out.
+func (m *Migrator) Check() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty {
+		return fmt.Errorf("%w: version %d is dirty", ErrSchemaDirty, status.Version)
+	}
+
+	if status.Pending() {
+		return fmt.Errorf("%w: version %d, needs %d", ErrSchemaBehind, status.Version, status.Latest)
+	}
+
+	return nil
+}
+
+// Close releases the connection of the migrator.
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.migrate.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+func latestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		} else if err != nil {
+			return 0, err
+		}
+
+		version = next
+	}
+}
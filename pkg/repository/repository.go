@@ -9,52 +9,50 @@ import (
 	_ "github.com/lib/pq"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.uber.org/zap"
-	"log"
 )
 
-func NewPostgresDB(cfg config.PostgresConfig, logger *zap.SugaredLogger) *sqlx.DB {
+func NewPostgresDB(ctx context.Context, cfg config.PostgresConfig) (*sqlx.DB, error) {
 	db, err := sqlx.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode))
 	if err != nil {
-		logger.Fatalf("failed to connect to postgres: %v", err)
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
 	}
 
-	err = db.Ping()
-	if err != nil {
-		logger.Fatalf("failed to ping database: %v", err)
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
 	}
 
-	return db
+	return db, nil
 }
 
-func NewRedisDB(ctx context.Context, cfg config.RedisConfig, logger *zap.SugaredLogger) *redis.Client {
+func NewRedisDB(ctx context.Context, cfg config.RedisConfig) (*redis.Client, error) {
 	redisClient := redis.NewClient(&redis.Options{
-		Addr:     cfg.Address,
+		Addr:     cfg.Addr(),
 		Password: cfg.Password,
 		DB:       cfg.DB,
 	})
 
-	_, err := redisClient.Ping(ctx).Result()
-	if err != nil {
-		logger.Fatalf("failed to ping Redis: %v", err)
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		redisClient.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
 	}
 
-	return redisClient
+	return redisClient, nil
 }
 
+// NewMongoDB returns the collection logs are written to. Its client is closed through
+// collection.Database().Client().
 func NewMongoDB(ctx context.Context, cfg config.MongoConfig) (*mongo.Collection, error) {
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURL))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URL()))
 	if err != nil {
-		log.Fatalf("failed to connect to MongoDB: %v", err)
+		return nil, fmt.Errorf("failed to connect to mongodb: %w", err)
 	}
 
-	err = client.Ping(ctx, nil)
-	if err != nil {
-		log.Fatalf("failed to ping MongoDB: %v", err)
+	if err = client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to ping mongodb: %w", err)
 	}
 
-	mongoDB := client.Database(cfg.DB).Collection("logs")
-
-	return mongoDB, nil
+	return client.Database(cfg.DBName).Collection("logs"), nil
 }
@@ -0,0 +1,45 @@
+package sql
+
+import (
+	"context"
+	_ "embed"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/jmoiron/sqlx"
+)
+
+// AccessRepository resolves the role a user has on a list, directly or through one of its items.
+type AccessRepository interface {
+	GetListAccess(ctx context.Context, userId, listId int) (todo.ListAccess, error)
+	GetItemAccess(ctx context.Context, userId, itemId int) (todo.ListAccess, error)
+}
+
+type AccessPostgres struct {
+	db *sqlx.DB
+}
+
+func NewAccessPostgres(db *sqlx.DB) *AccessPostgres {
+	return &AccessPostgres{db: db}
+}
+
+//go:embed query/GetListAccess.sql
+var getListAccess string
+
+func (r *AccessPostgres) GetListAccess(ctx context.Context, userId, listId int) (todo.ListAccess, error) {
+	var access todo.ListAccess
+
+	err := r.db.GetContext(ctx, &access, getListAccess, userId, listId)
+
+	return access, notFound(err, ErrListNotFound)
+}
+
+//go:embed query/GetItemAccess.sql
+var getItemAccess string
+
+// GetItemAccess returns the list the item belongs to and the role the user has on it.
+func (r *AccessPostgres) GetItemAccess(ctx context.Context, userId, itemId int) (todo.ListAccess, error) {
+	var access todo.ListAccess
+
+	err := r.db.GetContext(ctx, &access, getItemAccess, itemId, userId)
+
+	return access, notFound(err, ErrItemNotFound)
+}
@@ -0,0 +1,175 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"testing"
+)
+
+// TestUserIsolation checks that the queries themselves keep users away from lists and items
+// that are not shared with them, independently of the checks made by the services.
+func TestUserIsolation(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	lists := NewTodoListPostgres(db)
+	items := NewTodoItemPostgres(db)
+	access := NewAccessPostgres(db)
+	search := NewSearchPostgres(db)
+
+	alice := insertUser(t, db, "alice")
+	bob := insertUser(t, db, "bob")
+
+	listId, err := lists.Create(ctx, alice, todo.TodoList{Title: "groceries", Description: "weekly shopping"})
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	itemId, err := items.Create(ctx, listId, todo.TodoItem{Title: "milk"})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	hacked := "hacked"
+
+	assertUnchanged := func(t *testing.T) {
+		t.Helper()
+
+		list, err := lists.GetById(ctx, alice, listId)
+		if err != nil {
+			t.Fatalf("owner lost the list: %v", err)
+		}
+		if list.Title != "groceries" {
+			t.Errorf("list title = %q, want %q", list.Title, "groceries")
+		}
+
+		item, err := items.GetById(ctx, alice, itemId)
+		if err != nil {
+			t.Fatalf("owner lost the item: %v", err)
+		}
+		if item.Title != "milk" {
+			t.Errorf("item title = %q, want %q", item.Title, "milk")
+		}
+	}
+
+	t.Run("owner has access", func(t *testing.T) {
+		listAccess, err := access.GetListAccess(ctx, alice, listId)
+		if err != nil || listAccess.Role != todo.RoleOwner {
+			t.Fatalf("list access = %+v, %v; want owner", listAccess, err)
+		}
+
+		itemAccess, err := access.GetItemAccess(ctx, alice, itemId)
+		if err != nil || itemAccess.Role != todo.RoleOwner || itemAccess.ListId != listId {
+			t.Fatalf("item access = %+v, %v; want owner of list %d", itemAccess, err, listId)
+		}
+	})
+
+	t.Run("stranger has no access", func(t *testing.T) {
+		if _, err := access.GetListAccess(ctx, bob, listId); !errors.Is(err, ErrListNotFound) {
+			t.Errorf("list access error = %v, want %v", err, ErrListNotFound)
+		}
+
+		if _, err := access.GetItemAccess(ctx, bob, itemId); !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("item access error = %v, want %v", err, ErrItemNotFound)
+		}
+	})
+
+	t.Run("stranger cannot read", func(t *testing.T) {
+		listsPage, err := lists.GetAll(ctx, bob, firstPage)
+		if err != nil {
+			t.Fatalf("get lists: %v", err)
+		}
+		if len(listsPage.Data) != 0 || listsPage.Total != 0 {
+			t.Errorf("stranger sees lists: %+v", listsPage)
+		}
+
+		if _, err = lists.GetById(ctx, bob, listId); !errors.Is(err, ErrListNotFound) {
+			t.Errorf("get list error = %v, want %v", err, ErrListNotFound)
+		}
+
+		itemsPage, err := items.GetAll(ctx, bob, listId, firstPage, todo.ItemFilter{})
+		if err != nil {
+			t.Fatalf("get items: %v", err)
+		}
+		if len(itemsPage.Data) != 0 || itemsPage.Total != 0 {
+			t.Errorf("stranger sees items: %+v", itemsPage)
+		}
+
+		if _, err = items.GetById(ctx, bob, itemId); !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("get item error = %v, want %v", err, ErrItemNotFound)
+		}
+
+		hits, err := search.Search(ctx, bob, "milk", 10)
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(hits) != 0 {
+			t.Errorf("stranger finds %+v", hits)
+		}
+	})
+
+	t.Run("stranger cannot change", func(t *testing.T) {
+		if err := lists.Update(ctx, bob, listId, todo.UpdateListInput{Title: &hacked}); err != nil {
+			t.Fatalf("update list: %v", err)
+		}
+
+		if err := items.Update(ctx, bob, itemId, todo.UpdateItemInput{Title: &hacked}); err != nil {
+			t.Fatalf("update item: %v", err)
+		}
+
+		if err := items.Delete(ctx, bob, itemId); err != nil {
+			t.Fatalf("delete item: %v", err)
+		}
+
+		itemIds, err := lists.Delete(ctx, bob, listId)
+		if err != nil {
+			t.Fatalf("delete list: %v", err)
+		}
+		if len(itemIds) != 0 {
+			t.Errorf("stranger deleted items %v", itemIds)
+		}
+
+		assertUnchanged(t)
+	})
+
+	t.Run("viewer can read but not change", func(t *testing.T) {
+		if _, err := lists.Share(ctx, listId, "bob", todo.RoleViewer); err != nil {
+			t.Fatalf("share: %v", err)
+		}
+
+		itemAccess, err := access.GetItemAccess(ctx, bob, itemId)
+		if err != nil || itemAccess.Role != todo.RoleViewer {
+			t.Fatalf("item access = %+v, %v; want viewer", itemAccess, err)
+		}
+
+		if _, err = items.GetById(ctx, bob, itemId); err != nil {
+			t.Errorf("viewer cannot read the item: %v", err)
+		}
+
+		if err = lists.Update(ctx, bob, listId, todo.UpdateListInput{Title: &hacked}); err != nil {
+			t.Fatalf("update list: %v", err)
+		}
+		if err = items.Update(ctx, bob, itemId, todo.UpdateItemInput{Title: &hacked}); err != nil {
+			t.Fatalf("update item: %v", err)
+		}
+		if err = items.Delete(ctx, bob, itemId); err != nil {
+			t.Fatalf("delete item: %v", err)
+		}
+		if _, err = lists.Delete(ctx, bob, listId); err != nil {
+			t.Fatalf("delete list: %v", err)
+		}
+
+		assertUnchanged(t)
+	})
+
+	t.Run("revoked collaborator loses access", func(t *testing.T) {
+		if err := lists.RevokeAccess(ctx, listId, bob); err != nil {
+			t.Fatalf("revoke: %v", err)
+		}
+
+		if _, err := access.GetItemAccess(ctx, bob, itemId); !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("item access error = %v, want %v", err, ErrItemNotFound)
+		}
+	})
+}
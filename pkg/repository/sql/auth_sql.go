@@ -1,14 +1,19 @@
 package sql
 
 import (
+	"context"
 	_ "embed"
 	todo "github.com/dafuqqqyunglean/todoRestAPI"
 	"github.com/jmoiron/sqlx"
 )
 
 type AuthorizationRepository interface {
-	Create(user todo.User) (int, error)
-	Get(username, password string) (todo.User, error)
+	Create(ctx context.Context, user todo.User) (int, error)
+	Get(ctx context.Context, username string) (todo.User, error)
+	GetById(ctx context.Context, userId int) (todo.User, error)
+	UpdatePasswordHash(ctx context.Context, userId int, passwordHash string) error
+	SetDisabled(ctx context.Context, userId int, disabled bool) error
+	SetAdmin(ctx context.Context, userId int, admin bool) error
 }
 
 type AuthorizationPostgres struct {
@@ -22,11 +27,14 @@ func NewAuthorizationPostgres(db *sqlx.DB) *AuthorizationPostgres {
 //go:embed query/CreateUser.sql
 var createUser string
 
-func (r *AuthorizationPostgres) Create(user todo.User) (int, error) {
+func (r *AuthorizationPostgres) Create(ctx context.Context, user todo.User) (int, error) {
 	var id int
 
-	row := r.db.QueryRow(createUser, user.Name, user.Username, user.Password) // stores information about the returned row from db
+	row := r.db.QueryRowContext(ctx, createUser, user.Name, user.Username, user.Password) // stores information about the returned row from db
 	if err := row.Scan(&id); err != nil {
+		if isUniqueViolation(err) {
+			return 0, ErrUsernameTaken
+		}
 		return 0, err
 	}
 
@@ -36,10 +44,48 @@ func (r *AuthorizationPostgres) Create(user todo.User) (int, error) {
 //go:embed query/GetUser.sql
 var getUser string
 
-func (r *AuthorizationPostgres) Get(username, password string) (todo.User, error) {
+func (r *AuthorizationPostgres) Get(ctx context.Context, username string) (todo.User, error) {
 	var user todo.User
 
-	err := r.db.Get(&user, getUser, username, password)
+	err := r.db.GetContext(ctx, &user, getUser, username)
 
-	return user, err
+	return user, notFound(err, ErrUserNotFound)
+}
+
+//go:embed query/GetUserById.sql
+var getUserById string
+
+func (r *AuthorizationPostgres) GetById(ctx context.Context, userId int) (todo.User, error) {
+	var user todo.User
+
+	err := r.db.GetContext(ctx, &user, getUserById, userId)
+
+	return user, notFound(err, ErrUserNotFound)
+}
+
+//go:embed query/UpdatePasswordHash.sql
+var updatePasswordHash string
+
+func (r *AuthorizationPostgres) UpdatePasswordHash(ctx context.Context, userId int, passwordHash string) error {
+	_, err := r.db.ExecContext(ctx, updatePasswordHash, passwordHash, userId)
+
+	return err
+}
+
+//go:embed query/SetUserDisabled.sql
+var setUserDisabled string
+
+func (r *AuthorizationPostgres) SetDisabled(ctx context.Context, userId int, disabled bool) error {
+	_, err := r.db.ExecContext(ctx, setUserDisabled, disabled, userId)
+
+	return err
+}
+
+//go:embed query/SetUserAdmin.sql
+var setUserAdmin string
+
+func (r *AuthorizationPostgres) SetAdmin(ctx context.Context, userId int, admin bool) error {
+	_, err := r.db.ExecContext(ctx, setUserAdmin, admin, userId)
+
+	return err
 }
@@ -0,0 +1,40 @@
+package sql
+
+import (
+	"context"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/internal/testenv"
+	"github.com/jmoiron/sqlx"
+	"testing"
+)
+
+// newTestDB returns a fresh, migrated schema. Tests using it are skipped when Postgres is
+// not available.
+func newTestDB(t *testing.T) *sqlx.DB {
+	return testenv.Postgres(t)
+}
+
+func TestMain(m *testing.M) {
+	testenv.Main(m)
+}
+
+func insertUser(t *testing.T, db *sqlx.DB, username string) int {
+	t.Helper()
+
+	id, err := NewAuthorizationPostgres(db).Create(context.Background(), todo.User{
+		Name:     username,
+		Username: username,
+		Password: "hash",
+	})
+	if err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+
+	return id
+}
+
+var firstPage = todo.PageParams{
+	Limit:  todo.DefaultPageLimit,
+	SortBy: todo.SortById,
+	Order:  todo.OrderAsc,
+}
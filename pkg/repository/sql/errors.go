@@ -0,0 +1,47 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrListNotFound    = apperror.NewNotFound("list_not_found", "list not found")
+	ErrItemNotFound    = apperror.NewNotFound("item_not_found", "item not found")
+	ErrUserNotFound    = apperror.NewNotFound("user_not_found", "user not found")
+	ErrSessionNotFound = apperror.NewNotFound("session_not_found", "session not found")
+	ErrUsernameTaken   = apperror.NewConflict("username_taken", "username is already taken")
+	ErrInvalidCursor   = apperror.NewValidation("invalid_cursor", "invalid cursor")
+)
+
+// uniqueViolation is the Postgres error code for a violated unique constraint.
+const uniqueViolation = "23505"
+
+// notFound replaces sql.ErrNoRows with the domain error of the missing row. Rows the user
+// has no access to are missing too, so their existence is never revealed.
+func notFound(err error, notFoundErr *apperror.Error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFoundErr
+	}
+
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// rollback undoes a transaction after a failed statement, whose error is the one returned,
+// so a failed rollback is only logged, with the request id of ctx. A transaction ended by
+// a cancelled ctx is rolled back already.
+func rollback(ctx context.Context, tx interface{ Rollback() error }) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logging.FromContext(ctx).Warn("transaction rollback failed", zap.Error(err))
+	}
+}
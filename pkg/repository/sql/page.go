@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"strings"
+	"time"
+)
+
+// cursor points at the last row of a page: the value of the sort column and the id,
+// which breaks ties between rows with equal sort values.
+type cursor struct {
+	Value string `json:"v,omitempty"`
+	Id    int    `json:"id"`
+}
+
+func encodeCursor(params todo.PageParams, id int, title string, createdAt time.Time) string {
+	c := cursor{Id: id}
+
+	switch params.SortBy {
+	case todo.SortByTitle:
+		c.Value = title
+	case todo.SortByCreatedAt:
+		c.Value = createdAt.Format(time.RFC3339Nano)
+	}
+
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor also checks the value against the sort column, so a forged cursor is
+// rejected here rather than failing the cast in Postgres.
+func decodeCursor(s, sortBy string) (cursor, error) {
+	var c cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(b, &c); err != nil || c.Id <= 0 {
+		return c, ErrInvalidCursor
+	}
+
+	switch sortBy {
+	case todo.SortByTitle:
+		if c.Value == "" {
+			return c, ErrInvalidCursor
+		}
+	case todo.SortByCreatedAt:
+		if _, err = time.Parse(time.RFC3339Nano, c.Value); err != nil {
+			return c, ErrInvalidCursor
+		}
+	}
+
+	return c, nil
+}
+
+// keyset returns the condition selecting rows after the cursor and the ORDER BY clause for
+// the table alias. Placeholders are numbered from argId.
+func keyset(alias string, params todo.PageParams, argId int) (string, string, []interface{}, error) {
+	direction, operator := "ASC", ">"
+	if params.Order == todo.OrderDesc {
+		direction, operator = "DESC", "<"
+	}
+
+	var column, cast string
+	switch params.SortBy {
+	case todo.SortByTitle:
+		column, cast = alias+".title", "text"
+	case todo.SortByCreatedAt:
+		column, cast = alias+".created_at", "timestamptz"
+	}
+
+	orderBy := fmt.Sprintf("%s.id %s", alias, direction)
+	if column != "" {
+		orderBy = fmt.Sprintf("%s %s, %s", column, direction, orderBy)
+	}
+
+	if params.Cursor == "" {
+		return "", orderBy, nil, nil
+	}
+
+	c, err := decodeCursor(params.Cursor, params.SortBy)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if column == "" {
+		return fmt.Sprintf(" AND %s.id %s $%d", alias, operator, argId), orderBy, []interface{}{c.Id}, nil
+	}
+
+	condition := fmt.Sprintf(" AND (%s, %s.id) %s ($%d::%s, $%d)", column, alias, operator, argId, cast, argId+1)
+	return condition, orderBy, []interface{}{c.Value, c.Id}, nil
+}
+
+// likePattern makes a substring ILIKE pattern out of user input, escaping the wildcards.
+func likePattern(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s) + "%"
+}
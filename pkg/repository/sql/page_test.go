@@ -0,0 +1,47 @@
+package sql
+
+import (
+	"encoding/base64"
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"testing"
+	"time"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 123, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor string
+		sortBy string
+		valid  bool
+	}{
+		{"by id", encodeCursor(todo.PageParams{SortBy: todo.SortById}, 7, "milk", createdAt), todo.SortById, true},
+		{"by title", encodeCursor(todo.PageParams{SortBy: todo.SortByTitle}, 7, "milk", createdAt), todo.SortByTitle, true},
+		{"by created_at", encodeCursor(todo.PageParams{SortBy: todo.SortByCreatedAt}, 7, "milk", createdAt), todo.SortByCreatedAt, true},
+		{"not base64", "%%%", todo.SortById, false},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("id=7")), todo.SortById, false},
+		{"no id", base64.RawURLEncoding.EncodeToString([]byte(`{"v":"milk"}`)), todo.SortByTitle, false},
+		{"empty title", base64.RawURLEncoding.EncodeToString([]byte(`{"id":7}`)), todo.SortByTitle, false},
+		{"empty created_at", base64.RawURLEncoding.EncodeToString([]byte(`{"id":7}`)), todo.SortByCreatedAt, false},
+		{"unparsable created_at", base64.RawURLEncoding.EncodeToString([]byte(`{"v":"yesterday","id":7}`)), todo.SortByCreatedAt, false},
+		{"id cursor sorted by created_at", encodeCursor(todo.PageParams{SortBy: todo.SortById}, 7, "milk", createdAt), todo.SortByCreatedAt, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := decodeCursor(tt.cursor, tt.sortBy)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Fatalf("got %+v, %v, want ErrInvalidCursor", c, err)
+				}
+				return
+			}
+
+			if err != nil || c.Id != 7 {
+				t.Fatalf("got %+v, %v", c, err)
+			}
+		})
+	}
+}
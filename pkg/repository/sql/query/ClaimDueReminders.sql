@@ -0,0 +1,6 @@
+WITH claimed AS (
+    UPDATE todo_items SET reminded_at = now()
+    WHERE id IN (SELECT id FROM todo_items WHERE NOT done AND reminded_at IS NULL AND due_at <= $1 ORDER BY due_at LIMIT $2 FOR UPDATE SKIP LOCKED)
+    RETURNING id, title, due_at
+)
+SELECT c.id AS item_id, li.list_id, ul.user_id, c.title, c.due_at FROM claimed c INNER JOIN lists_items li on li.item_id = c.id INNER JOIN users_lists ul on ul.list_id = li.list_id
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT count(DISTINCT user_id) FROM sessions WHERE NOT revoked AND expires_at > now()
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT count(*) FROM todo_items ti INNER JOIN lists_items li on li.item_id = ti.id INNER JOIN users_lists ul on ul.list_id = li.list_id WHERE li.list_id = $1 AND ul.user_id = $2%s
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT count(*) FROM todo_lists tl INNER JOIN users_lists ul on tl.id = ul.list_id WHERE ul.user_id = $1
\ No newline at end of file
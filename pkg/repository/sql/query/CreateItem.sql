@@ -1 +1 @@
-INSERT INTO todo_items (title, description) values ($1, $2) RETURNING id
\ No newline at end of file
+INSERT INTO todo_items (title, description, priority, due_at) values ($1, $2, $3, $4) RETURNING id
\ No newline at end of file
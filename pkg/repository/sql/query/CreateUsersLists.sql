@@ -1 +1 @@
-INSERT INTO users_lists (user_id, list_id) VALUES ($1, $2)
\ No newline at end of file
+INSERT INTO users_lists (user_id, list_id, role) VALUES ($1, $2, 'owner')
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT u.id AS user_id, u.name, u.username, ul.role FROM users u INNER JOIN users_lists ul on ul.user_id = u.id WHERE ul.list_id = $1 ORDER BY u.id
\ No newline at end of file
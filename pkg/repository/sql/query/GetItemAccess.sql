@@ -0,0 +1 @@
+SELECT li.list_id, ul.role FROM users_lists ul INNER JOIN lists_items li on li.list_id = ul.list_id WHERE li.item_id = $1 AND ul.user_id = $2
\ No newline at end of file
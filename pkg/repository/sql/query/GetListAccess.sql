@@ -0,0 +1 @@
+SELECT list_id, role FROM users_lists WHERE user_id = $1 AND list_id = $2
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT id, user_id, refresh_token_hash, expires_at, revoked FROM sessions WHERE id = $1
@@ -1 +1 @@
-SELECT id FROM users WHERE username=$1 AND password_hash=$2
\ No newline at end of file
+SELECT id, name, username, password_hash, disabled, is_admin FROM users WHERE username=$1
@@ -0,0 +1 @@
+SELECT id, name, username, password_hash, disabled, is_admin FROM users WHERE id=$1
\ No newline at end of file
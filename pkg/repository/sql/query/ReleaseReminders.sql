@@ -0,0 +1 @@
+UPDATE todo_items SET reminded_at = NULL WHERE id = ANY($1)
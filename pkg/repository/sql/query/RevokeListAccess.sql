@@ -0,0 +1 @@
+DELETE FROM users_lists WHERE list_id = $1 AND user_id = $2
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE sessions SET revoked = true WHERE id = $1
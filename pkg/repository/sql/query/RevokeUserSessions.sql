@@ -0,0 +1 @@
+UPDATE sessions SET revoked = true WHERE user_id = $1 AND NOT revoked
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE sessions SET refresh_token_hash = $1, expires_at = $2 WHERE id = $3 AND refresh_token_hash = $4 AND NOT revoked
@@ -0,0 +1,16 @@
+WITH q AS (SELECT websearch_to_tsquery('simple', $2) AS query)
+SELECT * FROM (
+    SELECT 'list' AS kind, tl.id, tl.id AS list_id, tl.title,
+           ts_headline('simple', tl.title || ' ' || coalesce(tl.description, ''), q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2') AS snippet,
+           ts_rank(tl.search_vector, q.query) AS rank
+    FROM todo_lists tl INNER JOIN users_lists ul on tl.id = ul.list_id, q
+    WHERE ul.user_id = $1 AND tl.search_vector @@ q.query
+    UNION ALL
+    SELECT 'item' AS kind, ti.id, li.list_id, ti.title,
+           ts_headline('simple', ti.title || ' ' || coalesce(ti.description, ''), q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2') AS snippet,
+           ts_rank(ti.search_vector, q.query) AS rank
+    FROM todo_items ti INNER JOIN lists_items li on li.item_id = ti.id INNER JOIN users_lists ul on ul.list_id = li.list_id, q
+    WHERE ul.user_id = $1 AND ti.search_vector @@ q.query
+) hits
+ORDER BY rank DESC, kind, id
+LIMIT $3
\ No newline at end of file
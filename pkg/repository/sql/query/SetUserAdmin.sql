@@ -0,0 +1 @@
+UPDATE users SET is_admin = $1 WHERE id = $2
\ No newline at end of file
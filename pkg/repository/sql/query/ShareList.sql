@@ -0,0 +1 @@
+INSERT INTO users_lists (user_id, list_id, role) SELECT u.id, $2, $3 FROM users u WHERE u.username = $1 ON CONFLICT (user_id, list_id) DO UPDATE SET role = EXCLUDED.role RETURNING user_id
\ No newline at end of file
@@ -1 +1 @@
-UPDATE todo_lists tl SET %s FROM users_lists ul WHERE tl.id = ul.list_id AND ul.list_id = $%d AND ul.user_id = $%d
\ No newline at end of file
+UPDATE todo_lists tl SET %s FROM users_lists ul WHERE tl.id = ul.list_id AND ul.list_id = $%d AND ul.user_id = $%d AND ul.role IN ('owner', 'editor')
\ No newline at end of file
@@ -0,0 +1,52 @@
+package sql
+
+import (
+	"context"
+	_ "embed"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+type ReminderRepository interface {
+	ClaimDue(ctx context.Context, before time.Time, limit int) ([]todo.Reminder, error)
+	Release(ctx context.Context, itemIds []int) error
+}
+
+type ReminderPostgres struct {
+	db *sqlx.DB
+}
+
+func NewReminderPostgres(db *sqlx.DB) *ReminderPostgres {
+	return &ReminderPostgres{db: db}
+}
+
+//go:embed query/ClaimDueReminders.sql
+var claimDueReminders string
+
+// ClaimDue marks up to limit not done items due before the given time as reminded and
+// returns a reminder for every user of their lists. Rows locked by another instance are
+// skipped, so each item is claimed exactly once.
+func (r *ReminderPostgres) ClaimDue(ctx context.Context, before time.Time, limit int) ([]todo.Reminder, error) {
+	var reminders []todo.Reminder
+
+	err := r.db.SelectContext(ctx, &reminders, claimDueReminders, before, limit)
+
+	return reminders, err
+}
+
+//go:embed query/ReleaseReminders.sql
+var releaseReminders string
+
+// Release returns claimed items to the due ones, so their reminders are claimed again.
+func (r *ReminderPostgres) Release(ctx context.Context, itemIds []int) error {
+	ids := make([]int64, len(itemIds))
+	for i, id := range itemIds {
+		ids[i] = int64(id)
+	}
+
+	_, err := r.db.ExecContext(ctx, releaseReminders, pq.Array(ids))
+
+	return err
+}
@@ -0,0 +1,33 @@
+package sql
+
+import (
+	"context"
+	_ "embed"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/jmoiron/sqlx"
+)
+
+type SearchRepository interface {
+	Search(ctx context.Context, userId int, query string, limit int) ([]todo.SearchHit, error)
+}
+
+type SearchPostgres struct {
+	db *sqlx.DB
+}
+
+func NewSearchPostgres(db *sqlx.DB) *SearchPostgres {
+	return &SearchPostgres{db: db}
+}
+
+//go:embed query/Search.sql
+var search string
+
+// Search matches the query in websearch syntax against the lists and items the user has
+// access to, best ranked first.
+func (r *SearchPostgres) Search(ctx context.Context, userId int, query string, limit int) ([]todo.SearchHit, error) {
+	var hits []todo.SearchHit
+
+	err := r.db.SelectContext(ctx, &hits, search, userId, query, limit)
+
+	return hits, err
+}
@@ -0,0 +1,118 @@
+package sql
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+var ErrSessionNotRotated = errors.New("session was revoked or already rotated")
+
+type SessionRepository interface {
+	Create(ctx context.Context, session todo.Session) (int, error)
+	GetById(ctx context.Context, sessionId int) (todo.Session, error)
+	GetByRefreshToken(ctx context.Context, refreshTokenHash string) (todo.Session, error)
+	Rotate(ctx context.Context, sessionId int, oldHash, newHash string, expiresAt time.Time) error
+	Revoke(ctx context.Context, sessionId int) error
+	RevokeAll(ctx context.Context, userId int) error
+	CountActiveUsers(ctx context.Context) (int, error)
+}
+
+type SessionPostgres struct {
+	db *sqlx.DB
+}
+
+func NewSessionPostgres(db *sqlx.DB) *SessionPostgres {
+	return &SessionPostgres{db: db}
+}
+
+//go:embed query/CreateSession.sql
+var createSession string
+
+func (r *SessionPostgres) Create(ctx context.Context, session todo.Session) (int, error) {
+	var id int
+
+	row := r.db.QueryRowContext(ctx, createSession, session.UserId, session.RefreshTokenHash, session.ExpiresAt)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+//go:embed query/GetSessionById.sql
+var getSessionById string
+
+func (r *SessionPostgres) GetById(ctx context.Context, sessionId int) (todo.Session, error) {
+	var session todo.Session
+
+	err := r.db.GetContext(ctx, &session, getSessionById, sessionId)
+
+	return session, notFound(err, ErrSessionNotFound)
+}
+
+//go:embed query/GetSessionByRefreshToken.sql
+var getSessionByRefreshToken string
+
+func (r *SessionPostgres) GetByRefreshToken(ctx context.Context, refreshTokenHash string) (todo.Session, error) {
+	var session todo.Session
+
+	err := r.db.GetContext(ctx, &session, getSessionByRefreshToken, refreshTokenHash)
+
+	return session, notFound(err, ErrSessionNotFound)
+}
+
+//go:embed query/RotateSession.sql
+var rotateSession string
+
+// Rotate replaces the refresh token of a session only if oldHash is still the current one,
+// so two concurrent refreshes with the same token cannot both succeed.
+func (r *SessionPostgres) Rotate(ctx context.Context, sessionId int, oldHash, newHash string, expiresAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, rotateSession, newHash, expiresAt, sessionId, oldHash)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrSessionNotRotated
+	}
+
+	return nil
+}
+
+//go:embed query/RevokeSession.sql
+var revokeSession string
+
+func (r *SessionPostgres) Revoke(ctx context.Context, sessionId int) error {
+	_, err := r.db.ExecContext(ctx, revokeSession, sessionId)
+
+	return err
+}
+
+//go:embed query/RevokeUserSessions.sql
+var revokeUserSessions string
+
+// RevokeAll signs the user out everywhere.
+func (r *SessionPostgres) RevokeAll(ctx context.Context, userId int) error {
+	_, err := r.db.ExecContext(ctx, revokeUserSessions, userId)
+
+	return err
+}
+
+//go:embed query/CountActiveUsers.sql
+var countActiveUsers string
+
+// CountActiveUsers counts the users with a session that is neither revoked nor expired.
+func (r *SessionPostgres) CountActiveUsers(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, countActiveUsers)
+
+	return count, err
+}
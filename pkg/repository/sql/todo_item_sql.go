@@ -11,7 +11,7 @@ import (
 
 type TodoItemRepository interface {
 	Create(ctx context.Context, listId int, item todo.TodoItem) (int, error)
-	GetAll(ctx context.Context, userId, listId int) ([]todo.TodoItem, error)
+	GetAll(ctx context.Context, userId, listId int, params todo.PageParams, filter todo.ItemFilter) (todo.ItemsPage, error)
 	GetById(ctx context.Context, userId, itemId int) (todo.TodoItem, error)
 	Delete(ctx context.Context, userId, itemId int) error
 	Update(ctx context.Context, userId, itemId int, input todo.UpdateItemInput) error
@@ -40,15 +40,15 @@ func (r *TodoItemPostgres) Create(ctx context.Context, listId int, item todo.Tod
 	}
 
 	var itemId int
-	row := tx.QueryRow(createItem, item.Title, item.Description)
+	row := tx.QueryRowContext(ctx, createItem, item.Title, item.Description, item.Priority, item.DueAt)
 	if err := row.Scan(&itemId); err != nil {
-		tx.Rollback()
+		rollback(ctx, tx)
 		return 0, err
 	}
 
-	_, err = tx.Exec(createListsItems, listId, itemId)
+	_, err = tx.ExecContext(ctx, createListsItems, listId, itemId)
 	if err != nil {
-		tx.Rollback()
+		rollback(ctx, tx)
 		return 0, err
 	}
 
@@ -58,14 +58,56 @@ func (r *TodoItemPostgres) Create(ctx context.Context, listId int, item todo.Tod
 //go:embed query/GetAllItems.sql
 var getAllItems string
 
-func (r *TodoItemPostgres) GetAll(ctx context.Context, userId, listId int) ([]todo.TodoItem, error) {
-	var items []todo.TodoItem
+//go:embed query/CountItems.sql
+var countItems string
 
-	if err := r.db.SelectContext(ctx, &items, getAllItems, listId, userId); err != nil {
-		return nil, err
+func (r *TodoItemPostgres) GetAll(ctx context.Context, userId, listId int, params todo.PageParams, filter todo.ItemFilter) (todo.ItemsPage, error) {
+	var page todo.ItemsPage
+
+	filterQuery := ""
+	args := []interface{}{listId, userId}
+
+	if filter.Done != nil {
+		args = append(args, *filter.Done)
+		filterQuery += fmt.Sprintf(" AND ti.done = $%d", len(args))
+	}
+
+	if filter.Query != "" {
+		args = append(args, likePattern(filter.Query))
+		filterQuery += fmt.Sprintf(" AND (ti.title ILIKE $%d OR ti.description ILIKE $%d)", len(args), len(args))
+	}
+
+	if filter.Overdue {
+		filterQuery += " AND NOT ti.done AND ti.due_at < now()"
 	}
 
-	return items, nil
+	if filter.DueWithin > 0 {
+		args = append(args, filter.DueWithin.Seconds())
+		filterQuery += fmt.Sprintf(" AND NOT ti.done AND ti.due_at >= now() AND ti.due_at < now() + make_interval(secs => $%d)", len(args))
+	}
+
+	condition, orderBy, cursorArgs, err := keyset("ti", params, len(args)+1)
+	if err != nil {
+		return page, err
+	}
+
+	// one extra row tells whether there is a next page
+	query := fmt.Sprintf(getAllItems, filterQuery+condition, orderBy, params.Limit+1)
+	pageArgs := append(append([]interface{}{}, args...), cursorArgs...)
+
+	if err = r.db.SelectContext(ctx, &page.Data, query, pageArgs...); err != nil {
+		return page, err
+	}
+
+	if len(page.Data) > params.Limit {
+		page.Data = page.Data[:params.Limit]
+		last := page.Data[len(page.Data)-1]
+		page.NextCursor = encodeCursor(params, last.Id, last.Title, last.CreatedAt)
+	}
+
+	err = r.db.GetContext(ctx, &page.Total, fmt.Sprintf(countItems, filterQuery), args...)
+
+	return page, err
 }
 
 //go:embed query/GetItemById.sql
@@ -75,7 +117,7 @@ func (r *TodoItemPostgres) GetById(ctx context.Context, userId, itemId int) (tod
 	var item todo.TodoItem
 
 	if err := r.db.GetContext(ctx, &item, getItemById, itemId, userId); err != nil {
-		return item, err
+		return item, notFound(err, ErrItemNotFound)
 	}
 
 	return item, nil
@@ -111,11 +153,24 @@ func (r *TodoItemPostgres) Update(ctx context.Context, userId, itemId int, input
 	}
 
 	if input.Done != nil {
-		setValues = append(setValues, fmt.Sprintf("done = $%d", argId))
+		setValues = append(setValues, fmt.Sprintf("done = $%d, completed_at = CASE WHEN $%d THEN COALESCE(ti.completed_at, now()) END", argId, argId))
 		args = append(args, *input.Done)
 		argId++
 	}
 
+	if input.Priority != nil {
+		setValues = append(setValues, fmt.Sprintf("priority = $%d", argId))
+		args = append(args, *input.Priority)
+		argId++
+	}
+
+	if input.DueAt != nil {
+		// a new due date deserves a new reminder
+		setValues = append(setValues, fmt.Sprintf("due_at = $%d, reminded_at = NULL", argId))
+		args = append(args, *input.DueAt)
+		argId++
+	}
+
 	setQuery := strings.Join(setValues, ", ")
 
 	query := fmt.Sprintf(updateItem, setQuery, argId, argId+1)
@@ -11,10 +11,13 @@ import (
 
 type TodoListRepository interface {
 	Create(ctx context.Context, userId int, list todo.TodoList) (int, error)
-	GetAll(ctx context.Context, userId int) ([]todo.TodoList, error)
+	GetAll(ctx context.Context, userId int, params todo.PageParams) (todo.ListsPage, error)
 	GetById(ctx context.Context, userId, listId int) (todo.TodoList, error)
-	Delete(ctx context.Context, userId, listId int) error
+	Delete(ctx context.Context, userId, listId int) ([]int, error)
 	Update(ctx context.Context, userId, listId int, input todo.UpdateListInput) error
+	Share(ctx context.Context, listId int, username string, role todo.ListRole) (int, error)
+	GetCollaborators(ctx context.Context, listId int) ([]todo.Collaborator, error)
+	RevokeAccess(ctx context.Context, listId, userId int) error
 }
 
 type TodoListPostgres struct {
@@ -38,15 +41,15 @@ func (r *TodoListPostgres) Create(ctx context.Context, userId int, list todo.Tod
 	}
 
 	var id int
-	row := tx.QueryRow(createList, list.Title, list.Description) // stores information about the returned row from db
+	row := tx.QueryRowContext(ctx, createList, list.Title, list.Description) // stores information about the returned row from db
 	if err := row.Scan(&id); err != nil {
-		tx.Rollback()
+		rollback(ctx, tx)
 		return 0, err
 	}
 
-	_, err = tx.Exec(createUsersLists, userId, id)
+	_, err = tx.ExecContext(ctx, createUsersLists, userId, id)
 	if err != nil {
-		tx.Rollback()
+		rollback(ctx, tx)
 		return 0, err
 	}
 
@@ -56,12 +59,34 @@ func (r *TodoListPostgres) Create(ctx context.Context, userId int, list todo.Tod
 //go:embed query/GetAllLists.sql
 var getAllLists string
 
-func (r *TodoListPostgres) GetAll(ctx context.Context, userId int) ([]todo.TodoList, error) {
-	var lists []todo.TodoList
+//go:embed query/CountLists.sql
+var countLists string
 
-	err := r.db.SelectContext(ctx, &lists, getAllLists, userId)
+func (r *TodoListPostgres) GetAll(ctx context.Context, userId int, params todo.PageParams) (todo.ListsPage, error) {
+	var page todo.ListsPage
 
-	return lists, err
+	condition, orderBy, cursorArgs, err := keyset("tl", params, 2)
+	if err != nil {
+		return page, err
+	}
+
+	// one extra row tells whether there is a next page
+	query := fmt.Sprintf(getAllLists, condition, orderBy, params.Limit+1)
+	args := append([]interface{}{userId}, cursorArgs...)
+
+	if err = r.db.SelectContext(ctx, &page.Data, query, args...); err != nil {
+		return page, err
+	}
+
+	if len(page.Data) > params.Limit {
+		page.Data = page.Data[:params.Limit]
+		last := page.Data[len(page.Data)-1]
+		page.NextCursor = encodeCursor(params, last.Id, last.Title, last.CreatedAt)
+	}
+
+	err = r.db.GetContext(ctx, &page.Total, countLists, userId)
+
+	return page, err
 }
 
 //go:embed query/GetListById.sql
@@ -72,16 +97,36 @@ func (r *TodoListPostgres) GetById(ctx context.Context, userId, listId int) (tod
 
 	err := r.db.GetContext(ctx, &list, getListById, userId, listId)
 
-	return list, err
+	return list, notFound(err, ErrListNotFound)
 }
 
 //go:embed query/DeleteList.sql
 var deleteList string
 
-func (r *TodoListPostgres) Delete(ctx context.Context, userId, listId int) error {
-	_, err := r.db.ExecContext(ctx, deleteList, userId, listId)
+//go:embed query/DeleteListItems.sql
+var deleteListItems string
 
-	return err
+// Delete removes the list together with its items and returns the ids of the removed items.
+func (r *TodoListPostgres) Delete(ctx context.Context, userId, listId int) ([]int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// items go first: deleting the list cascades to lists_items, which links them to it
+	var itemIds []int
+	if err = tx.SelectContext(ctx, &itemIds, deleteListItems, userId, listId); err != nil {
+		rollback(ctx, tx)
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, deleteList, userId, listId)
+	if err != nil {
+		rollback(ctx, tx)
+		return nil, err
+	}
+
+	return itemIds, tx.Commit()
 }
 
 //go:embed query/UpdateList.sql
@@ -112,3 +157,34 @@ func (r *TodoListPostgres) Update(ctx context.Context, userId, listId int, input
 	_, err := r.db.ExecContext(ctx, query, args...)
 	return err
 }
+
+//go:embed query/ShareList.sql
+var shareList string
+
+// Share grants the user the role on the list, replacing the role they already had.
+func (r *TodoListPostgres) Share(ctx context.Context, listId int, username string, role todo.ListRole) (int, error) {
+	var userId int
+
+	err := r.db.GetContext(ctx, &userId, shareList, username, listId, role)
+	return userId, notFound(err, ErrUserNotFound)
+}
+
+//go:embed query/GetCollaborators.sql
+var getCollaborators string
+
+func (r *TodoListPostgres) GetCollaborators(ctx context.Context, listId int) ([]todo.Collaborator, error) {
+	var collaborators []todo.Collaborator
+
+	err := r.db.SelectContext(ctx, &collaborators, getCollaborators, listId)
+
+	return collaborators, err
+}
+
+//go:embed query/RevokeListAccess.sql
+var revokeListAccess string
+
+func (r *TodoListPostgres) RevokeAccess(ctx context.Context, listId, userId int) error {
+	_, err := r.db.ExecContext(ctx, revokeListAccess, listId, userId)
+
+	return err
+}
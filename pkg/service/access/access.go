@@ -0,0 +1,50 @@
+package access
+
+import (
+	"context"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+)
+
+// Authorizer is the one place deciding whether a user may act on a list or an item. Lists
+// and items the user has no role on are reported as not found, so their existence is not
+// revealed; a role too weak for the operation is reported as todo.ErrForbidden.
+type Authorizer interface {
+	List(ctx context.Context, userId, listId int, required todo.ListRole) (todo.ListAccess, error)
+	Item(ctx context.Context, userId, itemId int, required todo.ListRole) (todo.ListAccess, error)
+}
+
+type ImplAuthorizer struct {
+	repo sql.AccessRepository
+}
+
+func NewAuthorizer(repo sql.AccessRepository) *ImplAuthorizer {
+	return &ImplAuthorizer{repo: repo}
+}
+
+func (a *ImplAuthorizer) List(ctx context.Context, userId, listId int, required todo.ListRole) (todo.ListAccess, error) {
+	access, err := a.repo.GetListAccess(ctx, userId, listId)
+	if err != nil {
+		return access, err
+	}
+
+	return access, check(access, required)
+}
+
+// Item resolves the list of the item and the user's role on it in a single query.
+func (a *ImplAuthorizer) Item(ctx context.Context, userId, itemId int, required todo.ListRole) (todo.ListAccess, error) {
+	access, err := a.repo.GetItemAccess(ctx, userId, itemId)
+	if err != nil {
+		return access, err
+	}
+
+	return access, check(access, required)
+}
+
+func check(access todo.ListAccess, required todo.ListRole) error {
+	if !access.Role.Allows(required) {
+		return todo.ErrForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// PasswordHasher produces self-describing password hashes: everything needed to verify
+// a hash, including its salt and cost parameters, is encoded in the hash itself.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	NeedsRehash(encoded string) bool
+	Recognizes(encoded string) bool
+}
+
+// NewPasswordHasher hashes new passwords with the configured algorithm and keeps every other
+// known algorithm for verification, so stored hashes migrate to it on the next login.
+func NewPasswordHasher(cfg config.AuthConfig) (PasswordHasher, error) {
+	argon2id := NewArgon2idHasher(DefaultArgon2idParams)
+	bcryptHasher := NewBcryptHasher(bcrypt.DefaultCost)
+	legacy := NewLegacySHA1Hasher(cfg.LegacySalt)
+
+	switch cfg.PasswordHasher {
+	case "", "argon2id":
+		return NewUpgradingHasher(argon2id, bcryptHasher, legacy), nil
+	case "bcrypt":
+		return NewUpgradingHasher(bcryptHasher, argon2id, legacy), nil
+	default:
+		return nil, fmt.Errorf("unknown password hasher %q", cfg.PasswordHasher)
+	}
+}
+
+// UpgradingHasher hashes with the current algorithm but still verifies hashes produced by
+// the legacy ones, reporting them as needing a rehash.
+type UpgradingHasher struct {
+	current PasswordHasher
+	legacy  []PasswordHasher
+}
+
+func NewUpgradingHasher(current PasswordHasher, legacy ...PasswordHasher) *UpgradingHasher {
+	return &UpgradingHasher{
+		current: current,
+		legacy:  legacy,
+	}
+}
+
+func (h *UpgradingHasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *UpgradingHasher) Verify(password, encoded string) (bool, error) {
+	if h.current.Recognizes(encoded) {
+		return h.current.Verify(password, encoded)
+	}
+
+	for _, legacy := range h.legacy {
+		if legacy.Recognizes(encoded) {
+			return legacy.Verify(password, encoded)
+		}
+	}
+
+	return false, ErrUnknownHashFormat
+}
+
+func (h *UpgradingHasher) NeedsRehash(encoded string) bool {
+	return !h.current.Recognizes(encoded) || h.current.NeedsRehash(encoded)
+}
+
+func (h *UpgradingHasher) Recognizes(encoded string) bool {
+	if h.current.Recognizes(encoded) {
+		return true
+	}
+
+	for _, legacy := range h.legacy {
+		if legacy.Recognizes(encoded) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bcryptMaxBytes is the longest password bcrypt hashes, longer ones fail with
+// bcrypt.ErrPasswordTooLong.
+const bcryptMaxBytes = 72
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
+
+func (h *BcryptHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the second recommended option of RFC 9106.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const argon2idPrefix = "$argon2id$"
+
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+// Hash encodes the result in the PHC string format: $argon2id$v=19$m=...,t=...,p=...$salt$key.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	return err != nil || params != h.params
+}
+
+func (h *Argon2idHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+// LegacySHA1Hasher verifies hashes written by the first versions of the service: the hex
+// encoding of a shared salt followed by the SHA-1 digest of the password. It cannot
+// produce new hashes.
+type LegacySHA1Hasher struct {
+	prefix string
+}
+
+func NewLegacySHA1Hasher(salt string) *LegacySHA1Hasher {
+	return &LegacySHA1Hasher{prefix: hex.EncodeToString([]byte(salt))}
+}
+
+func (h *LegacySHA1Hasher) Hash(string) (string, error) {
+	return "", errors.New("legacy sha1 hasher is verify-only")
+}
+
+func (h *LegacySHA1Hasher) Verify(password, encoded string) (bool, error) {
+	digest := sha1.Sum([]byte(password))
+	expected := h.prefix + hex.EncodeToString(digest[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(encoded)) == 1, nil
+}
+
+func (h *LegacySHA1Hasher) NeedsRehash(string) bool {
+	return true
+}
+
+func (h *LegacySHA1Hasher) Recognizes(encoded string) bool {
+	return len(encoded) == len(h.prefix)+2*sha1.Size && strings.HasPrefix(encoded, h.prefix)
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const legacySalt = "j3qrh4jqw124617ajfhajs"
+
+// baselineHash is generatePasswordHash of the first versions of the service, verbatim.
+func baselineHash(password string) string {
+	hash := sha1.New()
+	hash.Write([]byte(password))
+
+	return fmt.Sprintf("%x", hash.Sum([]byte(legacySalt)))
+}
+
+// testArgon2idParams keep the tests fast.
+var testArgon2idParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestLegacySHA1Hasher(t *testing.T) {
+	hasher := NewLegacySHA1Hasher(legacySalt)
+	stored := baselineHash("Secret123")
+
+	tests := []struct {
+		name       string
+		password   string
+		encoded    string
+		recognizes bool
+		valid      bool
+	}{
+		{name: "baseline hash", password: "Secret123", encoded: stored, recognizes: true, valid: true},
+		{name: "wrong password", password: "Secret124", encoded: stored, recognizes: true},
+		{name: "other salt", password: "Secret123", encoded: NewLegacySHA1Hasher("other").prefix + stored[len(hasher.prefix):]},
+		{name: "truncated", password: "Secret123", encoded: stored[:len(stored)-1]},
+		{name: "bcrypt hash", password: "Secret123", encoded: "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasher.Recognizes(tt.encoded); got != tt.recognizes {
+				t.Fatalf("Recognizes %v", got)
+			}
+
+			valid, err := hasher.Verify(tt.password, tt.encoded)
+			if err != nil || valid != tt.valid {
+				t.Fatalf("Verify %v, %v", valid, err)
+			}
+		})
+	}
+
+	if !hasher.NeedsRehash(stored) {
+		t.Fatal("legacy hash does not need a rehash")
+	}
+	if _, err := hasher.Hash("Secret123"); err == nil {
+		t.Fatal("legacy hasher produced a hash")
+	}
+}
+
+func TestDecodeArgon2id(t *testing.T) {
+	encoded, err := NewArgon2idHasher(testArgon2idParams).Hash("Secret123")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil || params != testArgon2idParams || len(salt) != 16 || len(key) != 32 {
+		t.Fatalf("decoded %+v, %d byte salt, %d byte key, %v", params, len(salt), len(key), err)
+	}
+
+	for name, encoded := range map[string]string{
+		"too few parts":   "$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"other algorithm": "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5",
+		"other version":   "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5",
+		"bad params":      "$argon2id$v=19$m=64;t=1;p=1$c2FsdA$a2V5",
+		"bad salt":        "$argon2id$v=19$m=64,t=1,p=1$c2Fsd!$a2V5",
+		"bad key":         "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V!",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, _, err := decodeArgon2id(encoded); err == nil {
+				t.Fatal("decoded")
+			}
+		})
+	}
+}
+
+func TestUpgradingHasher(t *testing.T) {
+	argon2id := NewArgon2idHasher(testArgon2idParams)
+	bcryptHasher := NewBcryptHasher(4)
+	hasher := NewUpgradingHasher(argon2id, bcryptHasher, NewLegacySHA1Hasher(legacySalt))
+
+	current, err := hasher.Hash("Secret123")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	weaker, err := NewArgon2idHasher(Argon2idParams{Memory: 32, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("Secret123")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	bcrypted, err := bcryptHasher.Hash("Secret123")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		encoded     string
+		needsRehash bool
+	}{
+		{name: "current", encoded: current},
+		{name: "current algorithm, other params", encoded: weaker, needsRehash: true},
+		{name: "bcrypt", encoded: bcrypted, needsRehash: true},
+		{name: "legacy sha1", encoded: baselineHash("Secret123"), needsRehash: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid, err := hasher.Verify("Secret123", tt.encoded); err != nil || !valid {
+				t.Fatalf("Verify %v, %v", valid, err)
+			}
+			if valid, err := hasher.Verify("Secret124", tt.encoded); err != nil || valid {
+				t.Fatalf("Verify of a wrong password %v, %v", valid, err)
+			}
+			if got := hasher.NeedsRehash(tt.encoded); got != tt.needsRehash {
+				t.Fatalf("NeedsRehash %v", got)
+			}
+		})
+	}
+
+	if _, err := hasher.Verify("Secret123", "plain text"); !errors.Is(err, ErrUnknownHashFormat) {
+		t.Fatalf("unknown format: %v", err)
+	}
+	if hasher.Recognizes("plain text") {
+		t.Fatal("plain text recognized")
+	}
+}
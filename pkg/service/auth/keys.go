@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+)
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	retired   bool
+}
+
+// KeySet holds every configured JWT key. Tokens are signed with the active key and
+// verified with whichever non-retired key their kid header names, so a new key can be
+// activated without invalidating tokens signed by the previous one.
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	order  []string
+}
+
+func NewKeySet(cfg config.AuthConfig) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*signingKey, len(cfg.Keys))}
+
+	for _, keyCfg := range cfg.Keys {
+		if keyCfg.Id == "" {
+			return nil, errors.New("signing key without kid")
+		}
+		if _, ok := set.keys[keyCfg.Id]; ok {
+			return nil, fmt.Errorf("duplicate signing key %q", keyCfg.Id)
+		}
+
+		key, err := loadSigningKey(keyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", keyCfg.Id, err)
+		}
+
+		set.keys[key.id] = key
+		set.order = append(set.order, key.id)
+	}
+
+	active, ok := set.keys[cfg.ActiveKey]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q is not configured", cfg.ActiveKey)
+	}
+	if active.retired || active.signKey == nil {
+		return nil, fmt.Errorf("active signing key %q cannot sign tokens", cfg.ActiveKey)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+func loadSigningKey(cfg config.SigningKeyConfig) (*signingKey, error) {
+	key := &signingKey{id: cfg.Id, retired: cfg.Retired}
+
+	switch cfg.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		if cfg.Secret == "" {
+			return nil, errors.New("empty secret")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(cfg.Secret)
+		key.verifyKey = []byte(cfg.Secret)
+	case jwt.SigningMethodRS256.Alg():
+		key.method = jwt.SigningMethodRS256
+		if cfg.PrivateKeyFile != "" {
+			private, err := readPEM(cfg.PrivateKeyFile, func(b []byte) (interface{}, error) { return jwt.ParseRSAPrivateKeyFromPEM(b) })
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = private
+			key.verifyKey = &private.(*rsa.PrivateKey).PublicKey
+		} else {
+			public, err := readPEM(cfg.PublicKeyFile, func(b []byte) (interface{}, error) { return jwt.ParseRSAPublicKeyFromPEM(b) })
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = public
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		key.method = jwt.SigningMethodEdDSA
+		if cfg.PrivateKeyFile != "" {
+			private, err := readPEM(cfg.PrivateKeyFile, func(b []byte) (interface{}, error) { return jwt.ParseEdPrivateKeyFromPEM(b) })
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = private
+			key.verifyKey = private.(ed25519.PrivateKey).Public()
+		} else {
+			public, err := readPEM(cfg.PublicKeyFile, func(b []byte) (interface{}, error) { return jwt.ParseEdPublicKeyFromPEM(b) })
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = public
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	return key, nil
+}
+
+func readPEM(path string, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	if path == "" {
+		return nil, errors.New("neither private nor public key file is set")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(b)
+}
+
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.id
+
+	return token.SignedString(k.active.signKey)
+}
+
+func (k *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := k.keys[kid]
+	if !ok || key.retired {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+
+	return key.verifyKey, nil
+}
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public halves of the non-retired asymmetric keys. HMAC secrets are
+// never published, so HS256 tokens can only be verified by this service.
+func (k *KeySet) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(k.order))}
+
+	for _, id := range k.order {
+		key := k.keys[id]
+		if key.retired {
+			continue
+		}
+
+		jwk := JSONWebKey{KeyId: key.id, Use: "sig", Algorithm: key.method.Alg()}
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
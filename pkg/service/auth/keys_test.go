@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testKeys generates an RSA and an Ed25519 key and writes them as PEM files.
+type testKeys struct {
+	rsa           *rsa.PrivateKey
+	ed            ed25519.PrivateKey
+	rsaFile       string
+	edFile        string
+	edPublicFile  string
+	edPublicBytes ed25519.PublicKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+
+	write := func(name, blockType string, der []byte, err error) string {
+		if err != nil {
+			t.Fatalf("marshal %s: %v", name, err)
+		}
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+
+	edDER, edErr := x509.MarshalPKCS8PrivateKey(edKey)
+	edPublicDER, edPublicErr := x509.MarshalPKIXPublicKey(edPublic)
+
+	return testKeys{
+		rsa:           rsaKey,
+		ed:            edKey,
+		rsaFile:       write("rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil),
+		edFile:        write("ed.pem", "PRIVATE KEY", edDER, edErr),
+		edPublicFile:  write("ed.pub.pem", "PUBLIC KEY", edPublicDER, edPublicErr),
+		edPublicBytes: edPublic,
+	}
+}
+
+func (k testKeys) config(active string, retired ...string) config.AuthConfig {
+	keys := []config.SigningKeyConfig{
+		{Id: "hs", Algorithm: "HS256", Secret: "secret"},
+		{Id: "rsa", Algorithm: "RS256", PrivateKeyFile: k.rsaFile},
+		{Id: "ed", Algorithm: "EdDSA", PrivateKeyFile: k.edFile},
+		{Id: "ed-public", Algorithm: "EdDSA", PublicKeyFile: k.edPublicFile},
+	}
+	for i := range keys {
+		for _, id := range retired {
+			if keys[i].Id == id {
+				keys[i].Retired = true
+			}
+		}
+	}
+
+	return config.AuthConfig{ActiveKey: active, Keys: keys}
+}
+
+func newTestKeySet(t *testing.T, cfg config.AuthConfig) *KeySet {
+	t.Helper()
+
+	set, err := NewKeySet(cfg)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	return set
+}
+
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	keys := newTestKeys(t)
+
+	for _, previous := range []string{"hs", "rsa", "ed"} {
+		t.Run(previous, func(t *testing.T) {
+			token, err := newTestKeySet(t, keys.config(previous)).sign(testClaims())
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+
+			// another key is active now, the previous one still verifies
+			for _, active := range []string{"hs", "rsa", "ed"} {
+				if _, err := jwt.Parse(token, newTestKeySet(t, keys.config(active)).keyFunc); err != nil {
+					t.Fatalf("active %s: %v", active, err)
+				}
+			}
+
+			active := "hs"
+			if previous == "hs" {
+				active = "rsa"
+			}
+			if _, err := jwt.Parse(token, newTestKeySet(t, keys.config(active, previous)).keyFunc); err == nil {
+				t.Fatal("token of a retired key accepted")
+			}
+		})
+	}
+}
+
+func TestKeySetRejectsMismatchedKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	set := newTestKeySet(t, keys.config("rsa"))
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, testClaims())
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return signed
+	}
+
+	tests := map[string]string{
+		// the public key must not be usable as an HMAC secret
+		"HS256 under an RS256 kid": sign(jwt.SigningMethodHS256, "rsa", x509.MarshalPKCS1PublicKey(&keys.rsa.PublicKey)),
+		"RS256 under an HS256 kid": sign(jwt.SigningMethodRS256, "hs", keys.rsa),
+		"RS256 under an EdDSA kid": sign(jwt.SigningMethodRS256, "ed", keys.rsa),
+		"EdDSA under an RS256 kid": sign(jwt.SigningMethodEdDSA, "rsa", keys.ed),
+		"unknown kid":              sign(jwt.SigningMethodHS256, "other", []byte("secret")),
+		"no kid":                   sign(jwt.SigningMethodHS256, "", []byte("secret")),
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := jwt.Parse(token, set.keyFunc); err == nil {
+				t.Fatal("token accepted")
+			}
+		})
+	}
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := newTestKeySet(t, keys.config("hs", "ed")).JWKS()
+
+	// HS256 secrets are never published and retired keys are gone
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyId != "rsa" || jwks.Keys[1].KeyId != "ed-public" {
+		t.Fatalf("keys %+v", jwks.Keys)
+	}
+
+	rsaKey := jwks.Keys[0]
+	n, _ := base64.RawURLEncoding.DecodeString(rsaKey.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rsaKey.E)
+	if rsaKey.KeyType != "RSA" || rsaKey.Algorithm != "RS256" || rsaKey.Use != "sig" ||
+		new(big.Int).SetBytes(n).Cmp(keys.rsa.N) != 0 || new(big.Int).SetBytes(e).Int64() != int64(keys.rsa.E) {
+		t.Fatalf("rsa key %+v", rsaKey)
+	}
+
+	edKey := jwks.Keys[1]
+	x, _ := base64.RawURLEncoding.DecodeString(edKey.X)
+	if edKey.KeyType != "OKP" || edKey.Curve != "Ed25519" || edKey.Algorithm != "EdDSA" || !keys.edPublicBytes.Equal(ed25519.PublicKey(x)) {
+		t.Fatalf("ed25519 key %+v", edKey)
+	}
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+	keys := newTestKeys(t)
+
+	tests := map[string]config.AuthConfig{
+		"unknown active key":     keys.config("other"),
+		"retired active key":     keys.config("rsa", "rsa"),
+		"public-only active key": keys.config("ed-public"),
+		"duplicate kid": {ActiveKey: "hs", Keys: []config.SigningKeyConfig{
+			{Id: "hs", Algorithm: "HS256", Secret: "a"}, {Id: "hs", Algorithm: "HS256", Secret: "b"},
+		}},
+		"empty secret":     {ActiveKey: "hs", Keys: []config.SigningKeyConfig{{Id: "hs", Algorithm: "HS256"}}},
+		"missing key file": {ActiveKey: "rsa", Keys: []config.SigningKeyConfig{{Id: "rsa", Algorithm: "RS256", PrivateKeyFile: "missing.pem"}}},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewKeySet(cfg); err == nil {
+				t.Fatal("no error")
+			}
+		})
+	}
+}
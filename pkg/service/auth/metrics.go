@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/metrics"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"time"
+)
+
+var usersCreated = metrics.Factory.NewCounter(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Name:      "users_created_total",
+	Help:      "Users registered.",
+})
+
+// activeUsersTimeout bounds each count of the active users.
+const activeUsersTimeout = 5 * time.Second
+
+// ActiveUsers is a gauge of the users with an open session. Scrapes read the last value, which
+// Run counts in Postgres every interval, so scraping costs the database nothing.
+type ActiveUsers struct {
+	prometheus.Gauge
+	sessions sql.SessionRepository
+	interval time.Duration
+	logger   *zap.SugaredLogger
+}
+
+func NewActiveUsers(sessions sql.SessionRepository, interval time.Duration, logger *zap.SugaredLogger) *ActiveUsers {
+	return &ActiveUsers{
+		Gauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Name:      "active_users",
+			Help:      "Users with a session that is neither revoked nor expired.",
+		}),
+		sessions: sessions,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run counts the active users right away and then every interval until ctx is done.
+func (a *ActiveUsers) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh keeps the previous value when the count fails.
+func (a *ActiveUsers) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, activeUsersTimeout)
+	defer cancel()
+
+	count, err := a.sessions.CountActiveUsers(ctx)
+	if err != nil {
+		a.logger.Errorf("failed to count active users: %v", err)
+		return
+	}
+
+	a.Set(float64(count))
+}
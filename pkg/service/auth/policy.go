@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy decides which passwords are strong enough to sign up with. Passwords of
+// existing users are not rechecked when the policy changes.
+type PasswordPolicy struct {
+	cfg config.PasswordPolicyConfig
+	// maxBytes is the longest password the configured hasher accepts, 0 when it has no limit.
+	maxBytes int
+}
+
+func NewPasswordPolicy(cfg config.AuthConfig) *PasswordPolicy {
+	policy := &PasswordPolicy{cfg: cfg.PasswordPolicy}
+	if cfg.PasswordHasher == "bcrypt" {
+		policy.maxBytes = bcryptMaxBytes
+	}
+
+	return policy
+}
+
+// Check reports every rule the password breaks as a violation of the password field.
+// An empty password passes, the required binding tag already rejects it.
+func (p *PasswordPolicy) Check(username, password string) []apperror.Violation {
+	if password == "" {
+		return nil
+	}
+
+	var violations []apperror.Violation
+	violate := func(rule, message string) {
+		violations = append(violations, apperror.Violation{Field: "password", Rule: rule, Message: message})
+	}
+
+	length := len([]rune(password))
+	if length < p.cfg.MinLength {
+		violate("min", fmt.Sprintf("must be at least %d characters", p.cfg.MinLength))
+	}
+	if p.cfg.MaxLength > 0 && length > p.cfg.MaxLength {
+		violate("max", fmt.Sprintf("must be at most %d characters", p.cfg.MaxLength))
+	} else if p.maxBytes > 0 && len(password) > p.maxBytes {
+		violate("max", fmt.Sprintf("must be at most %d bytes", p.maxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.cfg.RequireUpper && !upper {
+		violate("upper", "must contain an uppercase letter")
+	}
+	if p.cfg.RequireLower && !lower {
+		violate("lower", "must contain a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !digit {
+		violate("digit", "must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		violate("symbol", "must contain a symbol")
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violate("username", "must not contain the username")
+	}
+
+	return violations
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := config.PasswordPolicyConfig{MinLength: 8, MaxLength: 128, RequireUpper: true, RequireDigit: true}
+
+	tests := []struct {
+		name     string
+		hasher   string
+		password string
+		want     []string
+	}{
+		{name: "strong", hasher: "argon2id", password: "Secret123"},
+		{name: "too short", hasher: "argon2id", password: "Sec1", want: []string{"min"}},
+		{name: "too long", hasher: "argon2id", password: "S1" + strings.Repeat("a", 127), want: []string{"max"}},
+		{name: "weak", hasher: "argon2id", password: "secretsecret", want: []string{"upper", "digit"}},
+		{name: "contains username", hasher: "argon2id", password: "Alice12345", want: []string{"username"}},
+		{name: "long for bcrypt", hasher: "argon2id", password: "S1" + strings.Repeat("a", 71)},
+		{name: "beyond bcrypt limit", hasher: "bcrypt", password: "S1" + strings.Repeat("a", 71), want: []string{"max"}},
+		{name: "at bcrypt limit", hasher: "bcrypt", password: "S1" + strings.Repeat("a", 70)},
+		// 41 characters but 80 bytes
+		{name: "multibyte beyond bcrypt limit", hasher: "bcrypt", password: "S1" + strings.Repeat("ё", 39), want: []string{"max"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := NewPasswordPolicy(config.AuthConfig{PasswordHasher: tt.hasher, PasswordPolicy: policy}).Check("alice", tt.password)
+
+			var rules []string
+			for _, v := range violations {
+				rules = append(rules, v.Rule)
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("violated %v, want %v", rules, tt.want)
+			}
+
+			if len(violations) == 0 && tt.hasher == "bcrypt" {
+				// whatever the policy lets through, the hasher must accept
+				if _, err := NewBcryptHasher(4).Hash(tt.password); err != nil {
+					t.Fatalf("hash: %v", err)
+				}
+			}
+		})
+	}
+}
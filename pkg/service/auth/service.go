@@ -2,94 +2,371 @@ package auth
 
 import (
 	"context"
-	"crypto/sha1"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/logging"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/validation"
 	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"sync"
 	"time"
 )
 
-const (
-	salt       = "j3qrh4jqw124617ajfhajs"
-	signingKey = "2k#4#%35FSFJl3ja#4353KSFjH"
-	tokenTTL   = 12 * time.Hour
+var (
+	ErrInvalidCredentials  = apperror.NewUnauthorized("invalid_credentials", "invalid username or password")
+	ErrInvalidRefreshToken = apperror.NewUnauthorized("invalid_refresh_token", "invalid refresh token")
+	ErrInvalidToken        = apperror.NewUnauthorized("invalid_token", "invalid access token")
+	ErrSessionRevoked      = apperror.NewUnauthorized("session_revoked", "session is revoked or expired")
+	ErrUserDisabled        = apperror.NewUnauthorized("user_disabled", "user is disabled")
+	ErrAdminRequired       = apperror.NewForbidden("admin_required", "only administrators can do this")
 )
 
 type AuthorizationService interface {
-	CreateUser(user todo.User) (int, error)
-	GenerateToken(username, password string) (string, error)
-	ParseToken(token string) (int, error)
+	CreateUser(ctx context.Context, input todo.SignUpInput) (int, error)
+	GenerateToken(ctx context.Context, username, password string) (todo.Tokens, error)
+	RefreshToken(ctx context.Context, refreshToken string) (todo.Tokens, error)
+	Logout(ctx context.Context, refreshToken string) error
+	ParseToken(ctx context.Context, token string) (int, error)
+	JWKS() JSONWebKeySet
+	ResetPassword(ctx context.Context, username, password string) error
+	DisableUser(ctx context.Context, username string) error
+	IssueToken(ctx context.Context, username string, ttl time.Duration) (todo.Tokens, error)
+	SetAdmin(ctx context.Context, username string, admin bool) error
+	RequireAdmin(ctx context.Context, userId int) error
 }
 
 type ImplAuthorizationService struct {
-	repo sql.AuthorizationRepository
-	ctx  context.Context
+	repo            sql.AuthorizationRepository
+	sessions        sql.SessionRepository
+	hasher          PasswordHasher
+	policy          *PasswordPolicy
+	keys            *KeySet
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	// dummyHash is verified against when the username is unknown, so that answers as slowly
+	// as a wrong password and response times do not tell which usernames exist.
+	dummyHash func() (string, error)
 }
 
 type tokenClaims struct {
 	jwt.RegisteredClaims
-	UserId int `json:"user_id"`
+	UserId    int `json:"user_id"`
+	SessionId int `json:"session_id"`
 }
 
-func NewAuthorizationService(repo sql.AuthorizationRepository, ctx context.Context) *ImplAuthorizationService {
+func NewAuthorizationService(repo sql.AuthorizationRepository, sessions sql.SessionRepository, hasher PasswordHasher,
+	keys *KeySet, cfg config.AuthConfig) *ImplAuthorizationService {
 	return &ImplAuthorizationService{
-		repo: repo,
-		ctx:  ctx,
+		repo:            repo,
+		sessions:        sessions,
+		hasher:          hasher,
+		policy:          NewPasswordPolicy(cfg),
+		keys:            keys,
+		accessTokenTTL:  cfg.AccessTokenTTL,
+		refreshTokenTTL: cfg.RefreshTokenTTL,
+		dummyHash: sync.OnceValues(func() (string, error) {
+			return hasher.Hash("dummy password")
+		}),
 	}
 }
 
-func (s *ImplAuthorizationService) CreateUser(user todo.User) (int, error) {
-	user.Password = generatePasswordHash(user.Password)
-	return s.repo.Create(user)
-}
+// CreateUser registers a user. A taken username is reported as sql.ErrUsernameTaken.
+func (s *ImplAuthorizationService) CreateUser(ctx context.Context, input todo.SignUpInput) (int, error) {
+	violations, err := validation.Violations(input)
+	if err != nil {
+		return 0, err
+	}
 
-func (s *ImplAuthorizationService) GenerateToken(username, password string) (string, error) {
-	user, err := s.repo.Get(username, generatePasswordHash(password))
+	violations = append(violations, s.policy.Check(input.Username, input.Password)...)
+	if err = validation.Fail(violations...); err != nil {
+		return 0, err
+	}
+
+	hash, err := s.hasher.Hash(input.Password)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
-		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenTTL)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-		user.Id,
+	id, err := s.repo.Create(ctx, todo.User{
+		Name:     input.Name,
+		Username: input.Username,
+		Password: hash,
 	})
+	if err != nil {
+		return 0, err
+	}
 
-	signedToken, err := token.SignedString([]byte(signingKey))
+	usersCreated.Inc()
+	return id, nil
+}
+
+// GenerateToken checks the credentials and opens a new session for the user.
+func (s *ImplAuthorizationService) GenerateToken(ctx context.Context, username, password string) (todo.Tokens, error) {
+	user, err := s.authenticate(ctx, username, password)
 	if err != nil {
-		return "", err
+		return todo.Tokens{}, err
 	}
 
-	return signedToken, nil
+	return s.openSession(ctx, user.Id, s.accessTokenTTL, s.refreshTokenTTL)
 }
 
-func (s *ImplAuthorizationService) ParseToken(accessToken string) (int, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
+// RefreshToken exchanges a refresh token for a new token pair. The presented refresh token
+// is rotated, so it can be used only once.
+func (s *ImplAuthorizationService) RefreshToken(ctx context.Context, refreshToken string) (todo.Tokens, error) {
+	oldHash := hashRefreshToken(refreshToken)
 
-		return []byte(signingKey), nil
-	})
+	session, err := s.sessions.GetByRefreshToken(ctx, oldHash)
+	if errors.Is(err, sql.ErrSessionNotFound) {
+		return todo.Tokens{}, ErrInvalidRefreshToken
+	} else if err != nil {
+		return todo.Tokens{}, err
+	}
+
+	if !isActive(session) {
+		return todo.Tokens{}, ErrSessionRevoked
+	}
+
+	newRefreshToken, err := generateRefreshToken()
 	if err != nil {
-		return 0, err
+		return todo.Tokens{}, err
+	}
+
+	err = s.sessions.Rotate(ctx, session.Id, oldHash, hashRefreshToken(newRefreshToken), time.Now().Add(s.refreshTokenTTL))
+	if errors.Is(err, sql.ErrSessionNotRotated) {
+		return todo.Tokens{}, ErrInvalidRefreshToken
+	} else if err != nil {
+		return todo.Tokens{}, err
+	}
+
+	accessToken, err := s.newAccessToken(session.UserId, session.Id, s.accessTokenTTL)
+	if err != nil {
+		return todo.Tokens{}, err
+	}
+
+	return todo.Tokens{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
+}
+
+// Logout revokes the session the refresh token belongs to. Access tokens issued for
+// the session are rejected by ParseToken from then on.
+func (s *ImplAuthorizationService) Logout(ctx context.Context, refreshToken string) error {
+	session, err := s.sessions.GetByRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, sql.ErrSessionNotFound) {
+		return ErrInvalidRefreshToken
+	} else if err != nil {
+		return err
+	}
+
+	return s.sessions.Revoke(ctx, session.Id)
+}
+
+func (s *ImplAuthorizationService) ParseToken(ctx context.Context, accessToken string) (int, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, s.keys.keyFunc)
+	if err != nil {
+		return 0, ErrInvalidToken.Wrap(err)
 	}
 
 	claims, ok := token.Claims.(*tokenClaims)
 	if !ok {
-		return 0, errors.New("token claims are not of type *tokenClaims")
+		return 0, ErrInvalidToken
+	}
+
+	// only a missing session means the token is revoked; an outage must not sign users out
+	session, err := s.sessions.GetById(ctx, claims.SessionId)
+	if errors.Is(err, sql.ErrSessionNotFound) {
+		return 0, ErrSessionRevoked
+	} else if err != nil {
+		return 0, err
+	}
+
+	if session.UserId != claims.UserId || !isActive(session) {
+		return 0, ErrSessionRevoked
 	}
 
 	return claims.UserId, nil
 }
 
-func generatePasswordHash(password string) string {
-	hash := sha1.New()
-	hash.Write([]byte(password))
+func (s *ImplAuthorizationService) JWKS() JSONWebKeySet {
+	return s.keys.JWKS()
+}
+
+// ResetPassword sets a new password, checked against the password policy, and signs the
+// user out of every session.
+func (s *ImplAuthorizationService) ResetPassword(ctx context.Context, username, password string) error {
+	if err := validation.Fail(s.policy.Check(username, password)...); err != nil {
+		return err
+	}
+
+	user, err := s.repo.Get(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.UpdatePasswordHash(ctx, user.Id, hash); err != nil {
+		return err
+	}
+
+	return s.sessions.RevokeAll(ctx, user.Id)
+}
+
+// DisableUser blocks sign-in for the user and revokes their sessions, which rejects the
+// access tokens already issued.
+func (s *ImplAuthorizationService) DisableUser(ctx context.Context, username string) error {
+	user, err := s.repo.Get(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.SetDisabled(ctx, user.Id, true); err != nil {
+		return err
+	}
+
+	return s.sessions.RevokeAll(ctx, user.Id)
+}
+
+// IssueToken opens a session for the user without their password, for service accounts
+// and scripts. Both tokens of the session live for ttl.
+func (s *ImplAuthorizationService) IssueToken(ctx context.Context, username string, ttl time.Duration) (todo.Tokens, error) {
+	if ttl <= 0 {
+		return todo.Tokens{}, todo.ErrInvalidInput.WithMessage("ttl must be positive")
+	}
+
+	user, err := s.repo.Get(ctx, username)
+	if err != nil {
+		return todo.Tokens{}, err
+	}
+
+	if user.Disabled {
+		return todo.Tokens{}, ErrUserDisabled
+	}
+
+	return s.openSession(ctx, user.Id, ttl, ttl)
+}
+
+// SetAdmin grants or revokes access to the admin endpoints.
+func (s *ImplAuthorizationService) SetAdmin(ctx context.Context, username string, admin bool) error {
+	user, err := s.repo.Get(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.SetAdmin(ctx, user.Id, admin)
+}
+
+// RequireAdmin returns ErrAdminRequired unless the user is an administrator. It reads the
+// flag on every call, so revoking it takes effect without new tokens.
+func (s *ImplAuthorizationService) RequireAdmin(ctx context.Context, userId int) error {
+	user, err := s.repo.GetById(ctx, userId)
+	if errors.Is(err, sql.ErrUserNotFound) {
+		return ErrAdminRequired
+	} else if err != nil {
+		return err
+	}
+
+	if !user.IsAdmin {
+		return ErrAdminRequired
+	}
+
+	return nil
+}
+
+// authenticate verifies the password against the stored hash and, when the hash was made
+// by an outdated algorithm or with outdated parameters, replaces it with a fresh one.
+func (s *ImplAuthorizationService) authenticate(ctx context.Context, username, password string) (todo.User, error) {
+	user, err := s.repo.Get(ctx, username)
+	if errors.Is(err, sql.ErrUserNotFound) {
+		if hash, err := s.dummyHash(); err == nil {
+			_, _ = s.hasher.Verify(password, hash)
+		}
+		return todo.User{}, ErrInvalidCredentials
+	} else if err != nil {
+		return todo.User{}, err
+	}
+
+	ok, err := s.hasher.Verify(password, user.Password)
+	if err != nil || !ok {
+		return todo.User{}, ErrInvalidCredentials
+	}
+
+	// checked after the password, so the error does not tell strangers the username exists
+	if user.Disabled {
+		return todo.User{}, ErrUserDisabled
+	}
+
+	if s.hasher.NeedsRehash(user.Password) {
+		// a failed upgrade must not block the login, the next one will retry it
+		hash, err := s.hasher.Hash(password)
+		if err == nil {
+			err = s.repo.UpdatePasswordHash(ctx, user.Id, hash)
+		}
+		if err != nil {
+			logging.FromContext(ctx).Warn("password hash upgrade failed", zap.Int("user_id", user.Id), zap.Error(err))
+		}
+	}
+
+	return user, nil
+}
+
+func (s *ImplAuthorizationService) openSession(ctx context.Context, userId int, accessTokenTTL, refreshTokenTTL time.Duration) (todo.Tokens, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return todo.Tokens{}, err
+	}
+
+	sessionId, err := s.sessions.Create(ctx, todo.Session{
+		UserId:           userId,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt:        time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return todo.Tokens{}, err
+	}
+
+	accessToken, err := s.newAccessToken(userId, sessionId, accessTokenTTL)
+	if err != nil {
+		return todo.Tokens{}, err
+	}
+
+	return todo.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (s *ImplAuthorizationService) newAccessToken(userId, sessionId int, ttl time.Duration) (string, error) {
+	return s.keys.sign(&tokenClaims{
+		jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		userId,
+		sessionId,
+	})
+}
+
+func isActive(session todo.Session) bool {
+	return !session.Revoked && time.Now().Before(session.ExpiresAt)
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
+// hashRefreshToken is what gets stored, so a database leak does not expose usable tokens.
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
 }
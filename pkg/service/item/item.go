@@ -2,52 +2,102 @@ package item
 
 import (
 	"context"
+	"fmt"
 	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/metrics"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/cache"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/access"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
 )
 
 type TodoItemService interface {
 	Create(ctx context.Context, userId, listId int, item todo.TodoItem) (int, error)
-	GetAll(ctx context.Context, userId, listId int) ([]todo.TodoItem, error)
+	GetAll(ctx context.Context, userId, listId int, params todo.PageParams, filter todo.ItemFilter) (todo.ItemsPage, error)
 	GetById(ctx context.Context, userId, itemId int) (todo.TodoItem, error)
 	Delete(ctx context.Context, userId, itemId int) error
 	Update(ctx context.Context, userId, itemId int, input todo.UpdateItemInput) error
 }
 
+var created = metrics.Factory.NewCounter(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Name:      "items_created_total",
+	Help:      "Todo items created.",
+})
+
 type ImplTodoItem struct {
-	repo     sql.TodoItemRepository
-	listRepo sql.TodoListRepository
-	cache    cache.RedisCache
+	repo   sql.TodoItemRepository
+	access access.Authorizer
+	cache  cache.Cache[todo.TodoItem]
+	pages  *cache.Loader[todo.ItemsPage]
+	events *cache.Invalidator
 }
 
-func NewTodoItemService(repo sql.TodoItemRepository, listRepo sql.TodoListRepository, cache cache.RedisCache) *ImplTodoItem {
+func NewTodoItemService(repo sql.TodoItemRepository, access access.Authorizer, cache cache.Cache[todo.TodoItem],
+	pages *cache.Loader[todo.ItemsPage], events *cache.Invalidator) *ImplTodoItem {
 	return &ImplTodoItem{
-		repo:     repo,
-		listRepo: listRepo,
-		cache:    cache,
+		repo:   repo,
+		access: access,
+		cache:  cache,
+		pages:  pages,
+		events: events,
 	}
 }
 
 func (s *ImplTodoItem) Create(ctx context.Context, userId, listId int, item todo.TodoItem) (int, error) {
-	_, err := s.listRepo.GetById(ctx, userId, listId)
+	if err := item.Validate(); err != nil {
+		return 0, err
+	}
+
+	if _, err := s.access.List(ctx, userId, listId, todo.RoleEditor); err != nil {
+		return 0, err
+	}
+
+	id, err := s.repo.Create(ctx, listId, item)
 	if err != nil {
 		return 0, err
 	}
 
-	return s.repo.Create(ctx, listId, item)
+	s.events.Publish(ctx, cache.ItemCreated{ListId: listId})
+	created.Inc()
+	return id, nil
 }
 
-func (s *ImplTodoItem) GetAll(ctx context.Context, userId, listId int) ([]todo.TodoItem, error) {
-	items, err := s.repo.GetAll(ctx, userId, listId)
-	if err != nil {
-		return nil, err
+// GetAll caches pages per list, so, like GetById, it checks access before looking into
+// the cache. Pages filtered relative to the current time are never cached.
+func (s *ImplTodoItem) GetAll(ctx context.Context, userId, listId int, params todo.PageParams, filter todo.ItemFilter) (todo.ItemsPage, error) {
+	if err := params.Validate(); err != nil {
+		return todo.ItemsPage{}, err
+	}
+
+	if _, err := s.access.List(ctx, userId, listId, todo.RoleViewer); err != nil {
+		return todo.ItemsPage{}, err
 	}
-	return items, nil
+
+	if filter.Overdue || filter.DueWithin > 0 {
+		return s.repo.GetAll(ctx, userId, listId, params, filter)
+	}
+
+	done := ""
+	if filter.Done != nil {
+		done = strconv.FormatBool(*filter.Done)
+	}
+	page := fmt.Sprintf("%d:%s:%s:%s:%s:%q", params.Limit, params.SortBy, params.Order, params.Cursor, done, filter.Query)
+
+	return s.pages.Load(ctx, cache.ListItems.Key(listId), page, func(ctx context.Context) (todo.ItemsPage, error) {
+		return s.repo.GetAll(ctx, userId, listId, params, filter)
+	})
 }
 
+// GetById checks access before looking into the cache: cached items are shared by all
+// collaborators of their list, so the cache alone cannot tell whether the user may see them.
 func (s *ImplTodoItem) GetById(ctx context.Context, userId, itemId int) (todo.TodoItem, error) {
-	item, err := s.cache.GetItem(ctx, userId, itemId)
+	if _, err := s.access.Item(ctx, userId, itemId, todo.RoleViewer); err != nil {
+		return todo.TodoItem{}, err
+	}
+
+	item, err := s.cache.Get(ctx, cache.Items.Key(itemId))
 	if err == nil {
 		return item, nil
 	}
@@ -57,17 +107,22 @@ func (s *ImplTodoItem) GetById(ctx context.Context, userId, itemId int) (todo.To
 		return item, err
 	}
 
-	s.cache.SetItem(ctx, userId, itemId, item)
+	s.cache.Set(ctx, cache.Items.Key(itemId), item)
 
 	return item, nil
 }
 
 func (s *ImplTodoItem) Delete(ctx context.Context, userId, itemId int) error {
-	err := s.repo.Delete(ctx, userId, itemId)
+	itemAccess, err := s.access.Item(ctx, userId, itemId, todo.RoleEditor)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Delete(ctx, userId, itemId)
 	if err != nil {
 		return err
 	}
-	s.cache.Delete(ctx, userId, itemId)
+	s.events.Publish(ctx, cache.ItemChanged{ListId: itemAccess.ListId, ItemId: itemId})
 
 	return nil
 }
@@ -77,11 +132,16 @@ func (s *ImplTodoItem) Update(ctx context.Context, userId, itemId int, input tod
 		return err
 	}
 
-	err := s.repo.Update(ctx, userId, itemId, input)
+	itemAccess, err := s.access.Item(ctx, userId, itemId, todo.RoleEditor)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Update(ctx, userId, itemId, input)
 	if err != nil {
 		return err
 	}
 
-	s.cache.Delete(ctx, userId, itemId)
+	s.events.Publish(ctx, cache.ItemChanged{ListId: itemAccess.ListId, ItemId: itemId})
 	return nil
 }
@@ -2,41 +2,89 @@ package list
 
 import (
 	"context"
+	"fmt"
 	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/metrics"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/cache"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/access"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type TodoListService interface {
 	Create(ctx context.Context, userId int, list todo.TodoList) (int, error)
-	GetAll(ctx context.Context, userId int) ([]todo.TodoList, error)
+	GetAll(ctx context.Context, userId int, params todo.PageParams) (todo.ListsPage, error)
 	GetById(ctx context.Context, userId, listId int) (todo.TodoList, error)
 	Delete(ctx context.Context, userId, listId int) error
 	Update(ctx context.Context, userId, listId int, input todo.UpdateListInput) error
+	Share(ctx context.Context, userId, listId int, input todo.ShareListInput) error
+	GetCollaborators(ctx context.Context, userId, listId int) ([]todo.Collaborator, error)
+	RevokeAccess(ctx context.Context, userId, listId, collaboratorId int) error
 }
 
+var created = metrics.Factory.NewCounter(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Name:      "lists_created_total",
+	Help:      "Todo lists created.",
+})
+
+var ErrLastOwner = apperror.NewConflict("last_owner", "the list must keep at least one owner")
+
 type ImplTodoList struct {
-	repo  sql.TodoListRepository
-	cache cache.RedisCache
+	repo   sql.TodoListRepository
+	access access.Authorizer
+	cache  cache.Cache[todo.TodoList]
+	pages  *cache.Loader[todo.ListsPage]
+	events *cache.Invalidator
 }
 
-func NewTodoListService(repo sql.TodoListRepository, cache cache.RedisCache) *ImplTodoList {
+func NewTodoListService(repo sql.TodoListRepository, access access.Authorizer, cache cache.Cache[todo.TodoList],
+	pages *cache.Loader[todo.ListsPage], events *cache.Invalidator) *ImplTodoList {
 	return &ImplTodoList{
-		repo:  repo,
-		cache: cache,
+		repo:   repo,
+		access: access,
+		cache:  cache,
+		pages:  pages,
+		events: events,
 	}
 }
 
 func (s *ImplTodoList) Create(ctx context.Context, userId int, list todo.TodoList) (int, error) {
-	return s.repo.Create(ctx, userId, list)
+	if err := list.Validate(); err != nil {
+		return 0, err
+	}
+
+	id, err := s.repo.Create(ctx, userId, list)
+	if err != nil {
+		return 0, err
+	}
+
+	s.events.Publish(ctx, cache.ListCreated{UserId: userId})
+	created.Inc()
+	return id, nil
 }
 
-func (s *ImplTodoList) GetAll(ctx context.Context, userId int) ([]todo.TodoList, error) {
-	return s.repo.GetAll(ctx, userId)
+func (s *ImplTodoList) GetAll(ctx context.Context, userId int, params todo.PageParams) (todo.ListsPage, error) {
+	if err := params.Validate(); err != nil {
+		return todo.ListsPage{}, err
+	}
+
+	page := fmt.Sprintf("%d:%s:%s:%s", params.Limit, params.SortBy, params.Order, params.Cursor)
+
+	return s.pages.Load(ctx, cache.UserLists.Key(userId), page, func(ctx context.Context) (todo.ListsPage, error) {
+		return s.repo.GetAll(ctx, userId, params)
+	})
 }
 
+// GetById checks access before looking into the cache: cached lists are shared by all
+// collaborators, so the cache alone cannot tell whether the user may see the list.
 func (s *ImplTodoList) GetById(ctx context.Context, userId, listId int) (todo.TodoList, error) {
-	list, err := s.cache.GetList(ctx, userId, listId)
+	if _, err := s.access.List(ctx, userId, listId, todo.RoleViewer); err != nil {
+		return todo.TodoList{}, err
+	}
+
+	list, err := s.cache.Get(ctx, cache.Lists.Key(listId))
 	if err == nil {
 		return list, nil
 	}
@@ -46,18 +94,27 @@ func (s *ImplTodoList) GetById(ctx context.Context, userId, listId int) (todo.To
 		return list, err
 	}
 
-	s.cache.SetList(ctx, userId, listId, list)
+	s.cache.Set(ctx, cache.Lists.Key(listId), list)
 
 	return list, nil
 }
 
 func (s *ImplTodoList) Delete(ctx context.Context, userId, listId int) error {
-	err := s.repo.Delete(ctx, userId, listId)
+	if _, err := s.access.List(ctx, userId, listId, todo.RoleOwner); err != nil {
+		return err
+	}
+
+	collaborators, err := s.repo.GetCollaborators(ctx, listId)
+	if err != nil {
+		return err
+	}
+
+	itemIds, err := s.repo.Delete(ctx, userId, listId)
 	if err != nil {
 		return err
 	}
-	s.cache.Delete(ctx, userId, listId)
 
+	s.events.Publish(ctx, cache.ListDeleted{ListId: listId, UserIds: userIds(collaborators), ItemIds: itemIds})
 	return nil
 }
 
@@ -66,11 +123,111 @@ func (s *ImplTodoList) Update(ctx context.Context, userId, listId int, input tod
 		return err
 	}
 
+	if _, err := s.access.List(ctx, userId, listId, todo.RoleEditor); err != nil {
+		return err
+	}
+
 	err := s.repo.Update(ctx, userId, listId, input)
 	if err != nil {
 		return err
 	}
 
-	s.cache.Delete(ctx, userId, listId)
+	collaborators, err := s.repo.GetCollaborators(ctx, listId)
+	if err != nil {
+		return err
+	}
+
+	s.events.Publish(ctx, cache.ListUpdated{ListId: listId, UserIds: userIds(collaborators)})
+	return nil
+}
+
+// Share grants the user with the given username a role on the list. Only owners can share,
+// and sharing with someone who already has access changes their role.
+func (s *ImplTodoList) Share(ctx context.Context, userId, listId int, input todo.ShareListInput) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := s.access.List(ctx, userId, listId, todo.RoleOwner); err != nil {
+		return err
+	}
+
+	collaborators, err := s.repo.GetCollaborators(ctx, listId)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range collaborators {
+		if c.Username == input.Username && c.Role == todo.RoleOwner && input.Role != todo.RoleOwner && countOwners(collaborators) == 1 {
+			return ErrLastOwner
+		}
+	}
+
+	collaboratorId, err := s.repo.Share(ctx, listId, input.Username, input.Role)
+	if err != nil {
+		return err
+	}
+
+	s.events.Publish(ctx, cache.AccessChanged{ListId: listId, UserId: collaboratorId})
+	return nil
+}
+
+func (s *ImplTodoList) GetCollaborators(ctx context.Context, userId, listId int) ([]todo.Collaborator, error) {
+	if _, err := s.access.List(ctx, userId, listId, todo.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetCollaborators(ctx, listId)
+}
+
+// RevokeAccess removes a collaborator from the list. Owners can remove anyone, other
+// collaborators can only remove themselves. The last owner cannot be removed.
+func (s *ImplTodoList) RevokeAccess(ctx context.Context, userId, listId, collaboratorId int) error {
+	required := todo.RoleOwner
+	if collaboratorId == userId {
+		required = todo.RoleViewer
+	}
+
+	if _, err := s.access.List(ctx, userId, listId, required); err != nil {
+		return err
+	}
+
+	collaborators, err := s.repo.GetCollaborators(ctx, listId)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range collaborators {
+		if c.UserId == collaboratorId && c.Role == todo.RoleOwner && countOwners(collaborators) == 1 {
+			return ErrLastOwner
+		}
+	}
+
+	err = s.repo.RevokeAccess(ctx, listId, collaboratorId)
+	if err != nil {
+		return err
+	}
+
+	s.events.Publish(ctx, cache.AccessChanged{ListId: listId, UserId: collaboratorId})
 	return nil
 }
+
+func countOwners(collaborators []todo.Collaborator) int {
+	owners := 0
+	for _, c := range collaborators {
+		if c.Role == todo.RoleOwner {
+			owners++
+		}
+	}
+
+	return owners
+}
+
+func userIds(collaborators []todo.Collaborator) []int {
+	ids := make([]int, 0, len(collaborators))
+	for _, c := range collaborators {
+		ids = append(ids, c.UserId)
+	}
+
+	return ids
+}
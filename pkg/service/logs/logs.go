@@ -0,0 +1,101 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/logstore"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"time"
+)
+
+type LogService interface {
+	Query(ctx context.Context, filter todo.LogFilter) (todo.LogsPage, error)
+}
+
+type ImplLogService struct {
+	repo logstore.LogRepository
+}
+
+func NewLogService(repo logstore.LogRepository) *ImplLogService {
+	return &ImplLogService{repo: repo}
+}
+
+func (s *ImplLogService) Query(ctx context.Context, filter todo.LogFilter) (todo.LogsPage, error) {
+	if filter.Limit < 1 || filter.Limit > todo.MaxPageLimit {
+		return todo.LogsPage{}, todo.ErrInvalidInput.WithMessage(fmt.Sprintf("limit must be between 1 and %d", todo.MaxPageLimit))
+	}
+
+	for _, level := range filter.Levels {
+		if _, err := zapcore.ParseLevel(level); err != nil {
+			return todo.LogsPage{}, todo.ErrInvalidInput.WithMessage(fmt.Sprintf("unknown level %q", level))
+		}
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return todo.LogsPage{}, todo.ErrInvalidInput.WithMessage("from must be before to")
+	}
+
+	return s.repo.Query(ctx, filter)
+}
+
+// Retention keeps the logs collection below the configured size by deleting the oldest
+// entries. Expired entries are removed by the TTL index EnsureIndexes creates.
+type Retention struct {
+	repo     logstore.LogRepository
+	maxBytes int64
+	interval time.Duration
+	logger   *zap.SugaredLogger
+}
+
+func NewRetention(repo logstore.LogRepository, cfg config.MongoRetentionConfig, logger *zap.SugaredLogger) *Retention {
+	return &Retention{
+		repo:     repo,
+		maxBytes: int64(cfg.MaxSizeMB) << 20,
+		interval: cfg.CheckInterval,
+		logger:   logger,
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (r *Retention) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.trim(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Retention) trim(ctx context.Context) {
+	size, count, err := r.repo.Size(ctx)
+	if err != nil {
+		r.logger.Errorf("failed to read the size of the logs collection: %v", err)
+		return
+	}
+
+	if size <= r.maxBytes || count == 0 {
+		return
+	}
+
+	// entries are about equally large, so the excess in bytes tells how many have to go
+	average := size / count
+	excess := (size - r.maxBytes + average - 1) / average
+
+	deleted, err := r.repo.DeleteOldest(ctx, excess)
+	if err != nil {
+		r.logger.Errorf("failed to delete the oldest log entries: %v", err)
+		return
+	}
+
+	r.logger.Infow("deleted the oldest log entries to keep the logs collection small",
+		"deleted", deleted, "size_bytes", size, "max_bytes", r.maxBytes)
+}
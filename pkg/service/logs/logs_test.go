@@ -0,0 +1,106 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// fakeRepository reports a collection of count entries taking size bytes.
+type fakeRepository struct {
+	size, count int64
+	sizeErr     error
+	deleted     []int64
+	queried     int
+}
+
+func (r *fakeRepository) EnsureIndexes(ctx context.Context, ttl time.Duration) error {
+	return nil
+}
+
+func (r *fakeRepository) Query(ctx context.Context, filter todo.LogFilter) (todo.LogsPage, error) {
+	r.queried++
+	return todo.LogsPage{}, nil
+}
+
+func (r *fakeRepository) Size(ctx context.Context) (int64, int64, error) {
+	return r.size, r.count, r.sizeErr
+}
+
+func (r *fakeRepository) DeleteOldest(ctx context.Context, n int64) (int64, error) {
+	r.deleted = append(r.deleted, n)
+	return n, nil
+}
+
+func TestQueryValidation(t *testing.T) {
+	from := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter todo.LogFilter
+		valid  bool
+	}{
+		{"defaults", todo.LogFilter{Limit: todo.DefaultPageLimit}, true},
+		{"levels and range", todo.LogFilter{Limit: 10, Levels: []string{"warn", "error"}, From: from, To: from.Add(time.Hour)}, true},
+		{"zero limit", todo.LogFilter{Limit: 0}, false},
+		{"limit over the maximum", todo.LogFilter{Limit: todo.MaxPageLimit + 1}, false},
+		{"unknown level", todo.LogFilter{Limit: 10, Levels: []string{"warn", "verbose"}}, false},
+		{"from equal to to", todo.LogFilter{Limit: 10, From: from, To: from}, false},
+		{"from after to", todo.LogFilter{Limit: 10, From: from.Add(time.Hour), To: from}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+
+			_, err := NewLogService(repo).Query(context.Background(), tt.filter)
+			if tt.valid {
+				if err != nil || repo.queried != 1 {
+					t.Fatalf("err %v, %d queries", err, repo.queried)
+				}
+				return
+			}
+
+			if !errors.Is(err, todo.ErrInvalidInput) || repo.queried != 0 {
+				t.Fatalf("err %v, %d queries", err, repo.queried)
+			}
+		})
+	}
+}
+
+func TestRetentionTrim(t *testing.T) {
+	const mb = 1 << 20
+
+	tests := []struct {
+		name        string
+		size, count int64
+		sizeErr     error
+		want        []int64
+	}{
+		{"below the limit", 9 * mb, 1000, nil, nil},
+		{"at the limit", 10 * mb, 1000, nil, nil},
+		{"empty", 0, 0, nil, nil},
+		// 1 KB entries, 1 MB over: 1024 of them go
+		{"over the limit", 11 * mb, 11 * 1024, nil, []int64{1024}},
+		// a partial entry over the limit still takes a whole one
+		{"rounds up", 10*mb + 1, 10 * 1024, nil, []int64{1}},
+		{"size unknown", 0, 0, errors.New("collStats failed"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{size: tt.size, count: tt.count, sizeErr: tt.sizeErr}
+			retention := NewRetention(repo, config.MongoRetentionConfig{MaxSizeMB: 10, CheckInterval: time.Minute}, zap.NewNop().Sugar())
+
+			retention.trim(context.Background())
+
+			if len(repo.deleted) != len(tt.want) || (len(tt.want) > 0 && repo.deleted[0] != tt.want[0]) {
+				t.Fatalf("deleted %v, want %v", repo.deleted, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package reminder
+
+import (
+	"context"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+	"go.uber.org/zap"
+	"slices"
+	"time"
+)
+
+// Notifier delivers reminder events. Implementations for e-mail, push or a message broker
+// can replace the default LogNotifier.
+type Notifier interface {
+	Notify(ctx context.Context, reminder todo.Reminder) error
+}
+
+type LogNotifier struct {
+	logger *zap.SugaredLogger
+}
+
+func NewLogNotifier(logger *zap.SugaredLogger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, reminder todo.Reminder) error {
+	n.logger.Infow("item is due soon",
+		"user_id", reminder.UserId,
+		"list_id", reminder.ListId,
+		"item_id", reminder.ItemId,
+		"title", reminder.Title,
+		"due_at", reminder.DueAt,
+	)
+
+	return nil
+}
+
+// Scheduler periodically claims items whose due date is within the lead time and emits
+// a reminder for each of them through the notifier.
+type Scheduler struct {
+	repo      sql.ReminderRepository
+	notifier  Notifier
+	interval  time.Duration
+	leadTime  time.Duration
+	batchSize int
+	logger    *zap.SugaredLogger
+}
+
+func NewScheduler(repo sql.ReminderRepository, notifier Notifier, cfg config.ReminderConfig, logger *zap.SugaredLogger) *Scheduler {
+	return &Scheduler{
+		repo:      repo,
+		notifier:  notifier,
+		interval:  cfg.Interval,
+		leadTime:  cfg.LeadTime,
+		batchSize: cfg.BatchSize,
+		logger:    logger,
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick sends the reminders of every item due now. Items whose reminder failed for any of
+// their users are released once the tick is over, so the next tick sends them again, also to
+// the users that already got them; releasing them earlier would claim them again right away.
+func (s *Scheduler) tick(ctx context.Context) {
+	var failed []int
+	defer func() {
+		if len(failed) == 0 {
+			return
+		}
+
+		if err := s.repo.Release(context.WithoutCancel(ctx), failed); err != nil {
+			s.logger.Errorf("failed to release reminders of items %v, they are not sent again: %v", failed, err)
+		}
+	}()
+
+	for {
+		reminders, err := s.repo.ClaimDue(ctx, time.Now().Add(s.leadTime), s.batchSize)
+		if err != nil {
+			s.logger.Errorf("failed to claim due reminders: %v", err)
+			return
+		}
+
+		for _, reminder := range reminders {
+			if err = s.notifier.Notify(ctx, reminder); err != nil {
+				s.logger.Errorf("failed to send reminder for item %d to user %d: %v", reminder.ItemId, reminder.UserId, err)
+				if !slices.Contains(failed, reminder.ItemId) {
+					failed = append(failed, reminder.ItemId)
+				}
+			}
+		}
+
+		// the limit applies to items and there is a reminder per collaborator, so a full
+		// batch may take one more round to find out nothing is left
+		if len(reminders) < s.batchSize {
+			return
+		}
+	}
+}
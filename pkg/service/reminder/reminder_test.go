@@ -0,0 +1,89 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
+	"go.uber.org/zap"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeRepository hands out the due reminders once, like the claim in Postgres does, until
+// their items are released.
+type fakeRepository struct {
+	due      []todo.Reminder
+	claimed  []todo.Reminder
+	released []int
+}
+
+func (r *fakeRepository) ClaimDue(_ context.Context, _ time.Time, limit int) ([]todo.Reminder, error) {
+	n := min(limit, len(r.due))
+	batch := r.due[:n]
+	r.due = r.due[n:]
+	r.claimed = append(r.claimed, batch...)
+
+	return batch, nil
+}
+
+func (r *fakeRepository) Release(_ context.Context, itemIds []int) error {
+	r.released = append(r.released, itemIds...)
+	for _, reminder := range r.claimed {
+		if slices.Contains(itemIds, reminder.ItemId) {
+			r.due = append(r.due, reminder)
+		}
+	}
+
+	return nil
+}
+
+// fakeNotifier fails the reminders of one user.
+type fakeNotifier struct {
+	failUser int
+	sent     []todo.Reminder
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, reminder todo.Reminder) error {
+	if reminder.UserId == n.failUser {
+		return errors.New("mailbox is full")
+	}
+	n.sent = append(n.sent, reminder)
+
+	return nil
+}
+
+func TestSchedulerReleasesFailedReminders(t *testing.T) {
+	repo := &fakeRepository{due: []todo.Reminder{
+		{ItemId: 1, UserId: 10},
+		{ItemId: 1, UserId: 20},
+		{ItemId: 2, UserId: 10},
+	}}
+	notifier := &fakeNotifier{failUser: 20}
+	scheduler := NewScheduler(repo, notifier, config.ReminderConfig{Interval: time.Minute, BatchSize: 2}, zap.NewNop().Sugar())
+
+	scheduler.tick(context.Background())
+
+	// the failed item is released once, after the tick, not claimed again within it
+	if !slices.Equal(repo.released, []int{1}) || len(notifier.sent) != 2 {
+		t.Fatalf("released %v, sent %v", repo.released, notifier.sent)
+	}
+
+	notifier.failUser = 0
+	scheduler.tick(context.Background())
+
+	if len(repo.released) != 1 || len(notifier.sent) != 4 || len(repo.due) != 0 {
+		t.Fatalf("released %v, sent %v, due %v", repo.released, notifier.sent, repo.due)
+	}
+}
+
+func TestSchedulerReleasesNothingWhenAllSent(t *testing.T) {
+	repo := &fakeRepository{due: []todo.Reminder{{ItemId: 1, UserId: 10}}}
+	NewScheduler(repo, &fakeNotifier{}, config.ReminderConfig{Interval: time.Minute, BatchSize: 10}, zap.NewNop().Sugar()).
+		tick(context.Background())
+
+	if len(repo.released) != 0 || len(repo.due) != 0 {
+		t.Fatalf("released %v, due %v", repo.released, repo.due)
+	}
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultLimit   = 20
+	MaxLimit       = 100
+	maxQueryLength = 255
+)
+
+var ErrInvalidQuery = apperror.NewValidation("invalid_search", "invalid search")
+
+type TodoSearchService interface {
+	Search(ctx context.Context, userId int, query string, limit int) ([]todo.SearchHit, error)
+}
+
+type ImplSearch struct {
+	repo sql.SearchRepository
+}
+
+func NewSearchService(repo sql.SearchRepository) *ImplSearch {
+	return &ImplSearch{repo: repo}
+}
+
+func (s *ImplSearch) Search(ctx context.Context, userId int, query string, limit int) ([]todo.SearchHit, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrInvalidQuery.WithMessage("query is empty")
+	}
+
+	if utf8.RuneCountInString(query) > maxQueryLength {
+		return nil, ErrInvalidQuery.WithMessage(fmt.Sprintf("query is longer than %d characters", maxQueryLength))
+	}
+
+	if limit < 1 || limit > MaxLimit {
+		return nil, ErrInvalidQuery.WithMessage(fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+	}
+
+	hits, err := s.repo.Search(ctx, userId, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hits {
+		hits[i].Snippet = escapeSnippet(hits[i].Snippet)
+	}
+
+	return hits, nil
+}
+
+var unescapeMarks = strings.NewReplacer("&lt;mark&gt;", "<mark>", "&lt;/mark&gt;", "</mark>")
+
+// escapeSnippet escapes the user content of a snippet while keeping the highlighting tags
+// inserted by ts_headline, so clients can render it as HTML.
+func escapeSnippet(snippet string) string {
+	return unescapeMarks.Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"context"
+	"errors"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"strings"
+	"testing"
+)
+
+type fakeRepository struct{}
+
+func (fakeRepository) Search(_ context.Context, _ int, query string, _ int) ([]todo.SearchHit, error) {
+	return []todo.SearchHit{{Title: query, Snippet: "<mark>" + query + "</mark> & <b>"}}, nil
+}
+
+func TestSearchValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		valid bool
+	}{
+		{name: "ascii", query: "milk", limit: DefaultLimit, valid: true},
+		{name: "longest", query: strings.Repeat("a", maxQueryLength), limit: DefaultLimit, valid: true},
+		// 255 characters, 510 bytes
+		{name: "longest non-ascii", query: strings.Repeat("ё", maxQueryLength), limit: DefaultLimit, valid: true},
+		{name: "too long", query: strings.Repeat("ё", maxQueryLength+1), limit: DefaultLimit},
+		{name: "blank", query: "  ", limit: DefaultLimit},
+		{name: "zero limit", query: "milk", limit: 0},
+		{name: "limit too high", query: "milk", limit: MaxLimit + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSearchService(fakeRepository{}).Search(context.Background(), 1, tt.query, tt.limit)
+			if tt.valid && err != nil {
+				t.Fatalf("error %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("error %v, want ErrInvalidQuery", err)
+			}
+		})
+	}
+}
+
+func TestSearchEscapesSnippets(t *testing.T) {
+	hits, err := NewSearchService(fakeRepository{}).Search(context.Background(), 1, "milk", DefaultLimit)
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	if want := "<mark>milk</mark> &amp; &lt;b&gt;"; hits[0].Snippet != want {
+		t.Fatalf("snippet %q, want %q", hits[0].Snippet, want)
+	}
+}
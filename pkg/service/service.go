@@ -1,35 +1,81 @@
 package service
 
 import (
-	"context"
+	todo "github.com/dafuqqqyunglean/todoRestAPI"
+	"github.com/dafuqqqyunglean/todoRestAPI/config"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/cache"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/repository/sql"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/access"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/auth"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/item"
 	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/list"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/reminder"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/service/search"
 	"github.com/go-redis/redis/v8"
 	"github.com/jmoiron/sqlx"
-	"time"
-)
-
-const (
-	cacheKey = "todo_item:%d:%d"
-	ttl      = time.Minute * 10
+	"go.uber.org/zap"
 )
 
 type Service struct {
-	AuthService *auth.ImplAuthorizationService
-	ListService *list.ImplTodoList
-	ItemService *item.ImplTodoItem
+	AuthService   *auth.ImplAuthorizationService
+	ListService   *list.ImplTodoList
+	ItemService   *item.ImplTodoItem
+	SearchService *search.ImplSearch
+	Reminders     *reminder.Scheduler
+	// CacheTTL is shared by every cache, so changing it applies to all of them.
+	CacheTTL *cache.TTL
 }
 
-func NewService(ctx context.Context, postgres *sqlx.DB, redis *redis.Client) *Service {
-	authService := auth.NewAuthorizationService(sql.NewAuthorizationPostgres(postgres), ctx)
-	todoLists := list.NewTodoListService(sql.NewTodoListPostgres(postgres), cache.NewRedisCache(redis, cacheKey, ttl))
-	todoItems := item.NewTodoItemService(sql.NewTodoItemPostgres(postgres), todoLists, cache.NewRedisCache(redis, cacheKey, ttl))
+func NewService(cfg config.Config, postgres *sqlx.DB, redis *redis.Client, logger *zap.SugaredLogger) (*Service, error) {
+	authService, err := NewAuthService(cfg.Auth, postgres)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := cache.NewCodec(cfg.Redis.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := cache.NewTTL(cfg.Cache.TTL)
+	listCache := cache.NewInstrumentedCache[todo.TodoList](cache.NewRedisCache[todo.TodoList](redis, codec, ttl), cache.NewStats("list"))
+	itemCache := cache.NewInstrumentedCache[todo.TodoItem](cache.NewRedisCache[todo.TodoItem](redis, codec, ttl), cache.NewStats("item"))
+	listPages := cache.NewLoader[todo.ListsPage](cache.NewRedisCollectionCache[todo.ListsPage](redis, codec, ttl), cache.NewStats("list_pages"))
+	itemPages := cache.NewLoader[todo.ItemsPage](cache.NewRedisCollectionCache[todo.ItemsPage](redis, codec, ttl), cache.NewStats("item_pages"))
+
+	events := cache.NewInvalidator(map[cache.Keyspace]cache.Deleter{
+		cache.Lists:     listCache,
+		cache.Items:     itemCache,
+		cache.UserLists: listPages,
+		cache.ListItems: itemPages,
+	})
+
+	authorizer := access.NewAuthorizer(sql.NewAccessPostgres(postgres))
+	todoLists := list.NewTodoListService(sql.NewTodoListPostgres(postgres), authorizer, listCache, listPages, events)
+	todoItems := item.NewTodoItemService(sql.NewTodoItemPostgres(postgres), authorizer, itemCache, itemPages, events)
+	reminders := reminder.NewScheduler(sql.NewReminderPostgres(postgres), reminder.NewLogNotifier(logger), cfg.Reminders, logger)
 	return &Service{
-		AuthService: authService,
-		ListService: todoLists,
-		ItemService: todoItems,
+		AuthService:   authService,
+		ListService:   todoLists,
+		ItemService:   todoItems,
+		SearchService: search.NewSearchService(sql.NewSearchPostgres(postgres)),
+		Reminders:     reminders,
+		CacheTTL:      ttl,
+	}, nil
+}
+
+// NewAuthService builds only the authorization service, for tools that manage users
+// without serving the API.
+func NewAuthService(cfg config.AuthConfig, postgres *sqlx.DB) (*auth.ImplAuthorizationService, error) {
+	hasher, err := auth.NewPasswordHasher(cfg)
+	if err != nil {
+		return nil, err
 	}
+
+	keys, err := auth.NewKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewAuthorizationService(sql.NewAuthorizationPostgres(postgres), sql.NewSessionPostgres(postgres), hasher, keys, cfg), nil
 }
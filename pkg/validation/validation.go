@@ -0,0 +1,104 @@
+// Package validation enforces the `binding` struct tags of the API inputs.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidInput = apperror.NewValidation("invalid_input", "invalid input")
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.SetTagName("binding")
+
+	// violations name fields the way clients send them
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+
+		return name
+	})
+
+	v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+
+	return v
+}
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// Struct checks the binding tags of s and reports every violated one.
+func Struct(s interface{}) error {
+	violations, err := Violations(s)
+	if err != nil {
+		return err
+	}
+
+	return Fail(violations...)
+}
+
+// Violations lists the violated binding tags of s, for callers that add checks of their own.
+func Violations(s interface{}) ([]apperror.Violation, error) {
+	err := validate.Struct(s)
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return nil, err
+	}
+
+	violations := make([]apperror.Violation, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		violations = append(violations, apperror.Violation{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+
+	return violations, nil
+}
+
+// Fail returns the validation error for the violations, or nil if there are none.
+func Fail(violations ...apperror.Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return ErrInvalidInput.WithMessage("request validation failed").WithViolations(violations...)
+}
+
+func message(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+		if fe.Param() == "1" {
+			unit = " character"
+		}
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "username":
+		return "may contain only letters, digits, '.', '_' and '-'"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	default:
+		return fmt.Sprintf("failed the %s check", fe.Tag())
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE sessions
+(
+    id serial not null unique,
+    user_id int references users (id) on delete cascade not null,
+    refresh_token_hash varchar(255) not null unique,
+    expires_at timestamptz not null,
+    revoked boolean not null default false,
+    created_at timestamptz not null default now()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE todo_items DROP COLUMN created_at;
+
+ALTER TABLE todo_lists DROP COLUMN created_at;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE todo_lists ADD COLUMN created_at timestamptz not null default now();
+
+ALTER TABLE todo_items ADD COLUMN created_at timestamptz not null default now();
\ No newline at end of file
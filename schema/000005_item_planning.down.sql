@@ -0,0 +1,9 @@
+DROP INDEX todo_items_due_at_idx;
+
+ALTER TABLE todo_items DROP COLUMN reminded_at;
+
+ALTER TABLE todo_items DROP COLUMN completed_at;
+
+ALTER TABLE todo_items DROP COLUMN due_at;
+
+ALTER TABLE todo_items DROP COLUMN priority;
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE todo_items ADD COLUMN priority smallint not null default 0 CHECK (priority BETWEEN 0 AND 3);
+
+ALTER TABLE todo_items ADD COLUMN due_at timestamptz;
+
+ALTER TABLE todo_items ADD COLUMN completed_at timestamptz;
+
+ALTER TABLE todo_items ADD COLUMN reminded_at timestamptz;
+
+CREATE INDEX todo_items_due_at_idx ON todo_items (due_at) WHERE NOT done AND reminded_at IS NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN is_admin boolean not null default false;
\ No newline at end of file
@@ -0,0 +1,7 @@
+// Package schema embeds the migrations, so the binary can migrate the database it serves.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var Migrations embed.FS
@@ -1,31 +1,147 @@
 package todo
 
-import "errors"
+import (
+	"fmt"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/apperror"
+	"github.com/dafuqqqyunglean/todoRestAPI/pkg/validation"
+	"time"
+)
 
 type User struct {
 	Id       int    `json:"-" db:"id"`
 	Name     string `json:"name"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"-" db:"password_hash"`
+	Disabled bool   `json:"-" db:"disabled"`
+	IsAdmin  bool   `json:"-" db:"is_admin"`
+}
+
+// SignUpInput is checked against the password policy too, which is configured at runtime
+// and so cannot be expressed with binding tags.
+type SignUpInput struct {
+	Name     string `json:"name" binding:"required,max=255"`
+	Username string `json:"username" binding:"required,min=3,max=32,username"`
+	Password string `json:"password" binding:"required"`
+}
+
+type Session struct {
+	Id               int       `db:"id"`
+	UserId           int       `db:"user_id"`
+	RefreshTokenHash string    `db:"refresh_token_hash"`
+	ExpiresAt        time.Time `db:"expires_at"`
+	Revoked          bool      `db:"revoked"`
+}
+
+type Tokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type TodoList struct {
-	Id          int    `json:"id" db:"id"`
-	Title       string `json:"title" db:"title" binding:"required"`
-	Description string `json:"description" db:"description"`
+	Id          int       `json:"id" db:"id"`
+	Title       string    `json:"title" db:"title" binding:"required,max=255"`
+	Description string    `json:"description" db:"description" binding:"max=255"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+func (l TodoList) Validate() error {
+	return validation.Struct(l)
+}
+
+type ListRole string
+
+const (
+	RoleOwner  ListRole = "owner"
+	RoleEditor ListRole = "editor"
+	RoleViewer ListRole = "viewer"
+)
+
+var (
+	ErrForbidden    = apperror.NewForbidden("forbidden", "not enough permissions for this list")
+	ErrInvalidInput = validation.ErrInvalidInput
+)
+
+func (r ListRole) Valid() bool {
+	return r.rank() > 0
+}
+
+// Allows reports whether the role grants at least the permissions of required:
+// owners can do everything editors can, and editors everything viewers can.
+func (r ListRole) Allows(required ListRole) bool {
+	return r.Valid() && r.rank() >= required.rank()
+}
+
+func (r ListRole) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ListAccess is the role a user has on a list.
+type ListAccess struct {
+	ListId int      `db:"list_id"`
+	Role   ListRole `db:"role"`
 }
 
 type UsersList struct {
 	Id     int
 	UserId int
 	ListId int
+	Role   ListRole
+}
+
+type Collaborator struct {
+	UserId   int      `json:"user_id" db:"user_id"`
+	Name     string   `json:"name" db:"name"`
+	Username string   `json:"username" db:"username"`
+	Role     ListRole `json:"role" db:"role"`
+}
+
+type ShareListInput struct {
+	Username string   `json:"username" binding:"required,max=255"`
+	Role     ListRole `json:"role" binding:"required,oneof=owner editor viewer"`
 }
 
+func (i ShareListInput) Validate() error {
+	return validation.Struct(i)
+}
+
+const (
+	PriorityNone = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
 type TodoItem struct {
-	Id          int    `json:"id" db:"id"`
-	Title       string `json:"title" db:"title" binding:"required"`
-	Description string `json:"description" db:"description"`
-	Done        bool   `json:"done" db:"done"`
+	Id          int        `json:"id" db:"id"`
+	Title       string     `json:"title" db:"title" binding:"required,max=255"`
+	Description string     `json:"description" db:"description" binding:"max=255"`
+	Done        bool       `json:"done" db:"done"`
+	Priority    int        `json:"priority" db:"priority" binding:"min=0,max=3"`
+	DueAt       *time.Time `json:"due_at,omitempty" db:"due_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+func (i TodoItem) Validate() error {
+	return validation.Struct(i)
+}
+
+// Reminder is sent to every collaborator of the list when one of its items approaches its due date.
+type Reminder struct {
+	ItemId int       `json:"item_id" db:"item_id"`
+	ListId int       `json:"list_id" db:"list_id"`
+	UserId int       `json:"user_id" db:"user_id"`
+	Title  string    `json:"title" db:"title"`
+	DueAt  time.Time `json:"due_at" db:"due_at"`
 }
 
 type ListsItem struct {
@@ -34,29 +150,140 @@ type ListsItem struct {
 	ItemId int
 }
 
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+
+	SortById        = "id"
+	SortByTitle     = "title"
+	SortByCreatedAt = "created_at"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// PageParams select one page of a keyset-paginated collection. Cursor is the opaque
+// next_cursor of the previous page and is only valid with the same sorting.
+type PageParams struct {
+	Limit  int
+	Cursor string
+	SortBy string
+	Order  string
+}
+
+func (p PageParams) Validate() error {
+	if p.Limit < 1 || p.Limit > MaxPageLimit {
+		return ErrInvalidInput.WithMessage(fmt.Sprintf("limit must be between 1 and %d", MaxPageLimit))
+	}
+
+	switch p.SortBy {
+	case SortById, SortByTitle, SortByCreatedAt:
+	default:
+		return ErrInvalidInput.WithMessage("sort must be one of id, title, created_at")
+	}
+
+	if p.Order != OrderAsc && p.Order != OrderDesc {
+		return ErrInvalidInput.WithMessage("order must be asc or desc")
+	}
+
+	return nil
+}
+
+// ItemFilter narrows an item collection. Overdue selects not done items past their due date,
+// DueWithin not done items due in that window from now.
+type ItemFilter struct {
+	Done      *bool
+	Query     string
+	Overdue   bool
+	DueWithin time.Duration
+}
+
+type ListsPage struct {
+	Data       []TodoList
+	NextCursor string
+	Total      int
+}
+
+type ItemsPage struct {
+	Data       []TodoItem
+	NextCursor string
+	Total      int
+}
+
+const (
+	SearchKindList = "list"
+	SearchKindItem = "item"
+)
+
+// SearchHit is a list or an item matching a search query. For lists ListId equals Id.
+// Matches in Snippet are wrapped in <mark> tags, the rest of it is HTML-escaped.
+type SearchHit struct {
+	Kind    string  `json:"kind" db:"kind"`
+	Id      int     `json:"id" db:"id"`
+	ListId  int     `json:"list_id" db:"list_id"`
+	Title   string  `json:"title" db:"title"`
+	Snippet string  `json:"snippet" db:"snippet"`
+	Rank    float64 `json:"rank" db:"rank"`
+}
+
+// LogFilter selects entries of the application log, newest first. Zero values do not
+// filter; Caller matches the beginning of the caller, e.g. "handler/" or "list/list.go".
+type LogFilter struct {
+	Levels    []string
+	From      time.Time
+	To        time.Time
+	RequestId string
+	UserId    int
+	Caller    string
+	Limit     int
+	Cursor    string
+}
+
+// LogEntry is an entry of the application log. Fields holds the zap fields other than the
+// request and user ids.
+type LogEntry struct {
+	Id        string                 `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Caller    string                 `json:"caller,omitempty"`
+	Logger    string                 `json:"logger,omitempty"`
+	Stack     string                 `json:"stack,omitempty"`
+	RequestId string                 `json:"request_id,omitempty"`
+	UserId    int                    `json:"user_id,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+type LogsPage struct {
+	Data       []LogEntry
+	NextCursor string
+}
+
 type UpdateListInput struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
+	Title       *string `json:"title" binding:"omitnil,min=1,max=255"`
+	Description *string `json:"description" binding:"omitnil,max=255"`
 }
 
 func (i UpdateListInput) Validate() error {
 	if i.Title == nil && i.Description == nil {
-		return errors.New("update structure has no values")
+		return ErrInvalidInput.WithMessage("update structure has no values")
 	}
 
-	return nil
+	return validation.Struct(i)
 }
 
 type UpdateItemInput struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	Done        *bool   `json:"done"`
+	Title       *string    `json:"title" binding:"omitnil,min=1,max=255"`
+	Description *string    `json:"description" binding:"omitnil,max=255"`
+	Done        *bool      `json:"done"`
+	Priority    *int       `json:"priority" binding:"omitnil,min=0,max=3"`
+	DueAt       *time.Time `json:"due_at"`
 }
 
 func (i UpdateItemInput) Validate() error {
-	if i.Title == nil && i.Description == nil && i.Done == nil {
-		return errors.New("update structure has no values")
+	if i.Title == nil && i.Description == nil && i.Done == nil && i.Priority == nil && i.DueAt == nil {
+		return ErrInvalidInput.WithMessage("update structure has no values")
 	}
 
-	return nil
+	return validation.Struct(i)
 }